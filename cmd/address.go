@@ -0,0 +1,84 @@
+/*
+ * Copyright 2025 Simon Emms <simon@simonemms.com>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+)
+
+const defaultTemporalPort = "7233"
+
+// Normalises the Temporal address so that it can be dialled. Schemes and
+// paths are removed (eg, https://my-ns.tmprl.cloud:7233), IPv6 literals are
+// bracketed and the default port is added if missing.
+func normaliseTemporalAddress(address string) (string, error) {
+	original := address
+
+	address = strings.TrimSpace(address)
+	if i := strings.Index(address, "://"); i >= 0 {
+		address = address[i+3:]
+	}
+	// Handle "dns:///host:port"
+	address = strings.TrimLeft(address, "/")
+	if i := strings.Index(address, "/"); i >= 0 {
+		address = address[:i]
+	}
+
+	if address == "" {
+		return "", fmt.Errorf("invalid temporal address %q: address is empty", original)
+	}
+
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		if ip := net.ParseIP(strings.Trim(address, "[]")); ip != nil {
+			// IP address without a port - this may be an unbracketed IPv6 literal
+			host = ip.String()
+			port = defaultTemporalPort
+		} else if !strings.Contains(address, ":") {
+			// Hostname without a port
+			host = address
+			port = defaultTemporalPort
+		} else {
+			return "", fmt.Errorf("invalid temporal address %q: %w", original, err)
+		}
+	}
+
+	if host == "" {
+		return "", fmt.Errorf("invalid temporal address %q: host is empty", original)
+	}
+	if p, err := strconv.Atoi(port); err != nil || p < 1 || p > 65535 {
+		return "", fmt.Errorf("invalid temporal address %q: port must be between 1 and 65535", original)
+	}
+
+	return net.JoinHostPort(host, port), nil
+}
+
+// gRPC uses the standard proxy envvars when dialling, so log them to make
+// connection issues easier to debug
+func logProxySettings() {
+	for _, e := range []string{"HTTPS_PROXY", "https_proxy", "NO_PROXY", "no_proxy"} {
+		if v, ok := os.LookupEnv(e); ok {
+			log.Debug().Str("envvar", e).Str("value", v).Msg("Using proxy setting for Temporal connection")
+		}
+	}
+}
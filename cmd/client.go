@@ -0,0 +1,103 @@
+/*
+ * Copyright 2025 Simon Emms <simon@simonemms.com>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"crypto/tls"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/mrsimonemms/golang-helpers/temporal"
+	tsw "github.com/mrsimonemms/temporal-serverless-workflow/pkg/workflow"
+	"github.com/rs/zerolog/log"
+	"go.temporal.io/sdk/client"
+	"go.temporal.io/sdk/converter"
+	"go.temporal.io/sdk/workflow"
+)
+
+// Connects to Temporal with the connection flags. This is shared by the
+// worker and the commands that talk to Temporal.
+func newClient(identity string) (client.Client, error) {
+	address, err := normaliseTemporalAddress(rootOpts.TemporalAddress)
+	if err != nil {
+		return nil, fmt.Errorf("invalid temporal address: %w", err)
+	}
+	log.Debug().Str("address", address).Msg("Using Temporal address")
+	logProxySettings()
+
+	connectionOpts := client.ConnectionOptions{}
+	if rootOpts.TemporalTLSEnabled {
+		// Use new to avoid a golint false positive
+		log.Debug().Msg("Enabling TLS connection")
+		connectionOpts.TLS = new(tls.Config)
+	}
+	apiKey := rootOpts.TemporalAPIKey
+	if rootOpts.TemporalAPIKeyFile != "" {
+		// The file takes precedence over the inline key
+		log.Debug().Str("path", rootOpts.TemporalAPIKeyFile).Msg("Reading API key from file")
+		key, err := os.ReadFile(filepath.Clean(rootOpts.TemporalAPIKeyFile))
+		if err != nil {
+			return nil, fmt.Errorf("unable to read api key file: %w", err)
+		}
+		apiKey = strings.TrimSpace(string(key))
+		if apiKey == "" {
+			return nil, fmt.Errorf("api key file is empty: %s", rootOpts.TemporalAPIKeyFile)
+		}
+	}
+
+	var creds client.Credentials
+	if apiKey != "" {
+		log.Debug().Msg("Using API key for authentcation")
+		creds = client.NewAPIKeyStaticCredentials(apiKey)
+	}
+
+	var dataConverter converter.DataConverter
+	if rootOpts.ExactNumbers {
+		log.Debug().Msg("Decoding JSON numbers exactly")
+		dataConverter = tsw.NewDataConverter()
+	}
+	if rootOpts.ConvertData {
+		codec, err := newReloadingCodec(rootOpts.ConvertKeyPath, rootOpts.ConvertKeyID, rootOpts.ConvertKeyReload)
+		if err != nil {
+			return nil, err
+		}
+		if dataConverter == nil {
+			dataConverter = converter.GetDefaultDataConverter()
+		}
+		dataConverter = converter.NewCodecDataConverter(dataConverter, codec)
+	}
+
+	var propagators []workflow.ContextPropagator
+	if len(rootOpts.PropagateVariables) > 0 {
+		log.Debug().Strs("variables", rootOpts.PropagateVariables).Msg("Propagating variables")
+		propagators = append(propagators, tsw.NewContextPropagator(rootOpts.PropagateVariables))
+	}
+
+	return dialWithRetry(client.Options{
+		ConnectionOptions:  connectionOpts,
+		ContextPropagators: propagators,
+		Credentials:        creds,
+		HostPort:           address,
+		Identity:           identity,
+		Interceptors:       clientInterceptors,
+		Namespace:          rootOpts.TemporalNamespace,
+		DataConverter:      dataConverter,
+		Logger:             temporal.NewZerologHandler(&log.Logger),
+	}, rootOpts.TemporalDialTimeout)
+}
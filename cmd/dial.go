@@ -0,0 +1,54 @@
+/*
+ * Copyright 2025 Simon Emms <simon@simonemms.com>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"go.temporal.io/sdk/client"
+)
+
+const (
+	initialDialBackoff = time.Second
+	maxDialBackoff     = time.Second * 30
+)
+
+// Dials the Temporal server, retrying with an exponential backoff until the
+// retry window has passed. This allows the worker to start before Temporal
+// is available, such as in Kubernetes. A window of 0 only tries once.
+func dialWithRetry(opts client.Options, window time.Duration) (client.Client, error) {
+	deadline := time.Now().Add(window)
+	backoff := initialDialBackoff
+
+	for attempt := 1; ; attempt++ {
+		c, err := client.Dial(opts)
+		if err == nil {
+			return c, nil
+		}
+
+		if window <= 0 || time.Now().Add(backoff).After(deadline) {
+			return nil, fmt.Errorf("unable to connect after %d attempt(s): %w", attempt, err)
+		}
+
+		log.Warn().Err(err).Int("attempt", attempt).Dur("retryIn", backoff).Msg("Unable to connect to Temporal - retrying")
+		time.Sleep(backoff)
+
+		backoff = min(backoff*2, maxDialBackoff)
+	}
+}
@@ -0,0 +1,75 @@
+/*
+ * Copyright 2025 Simon Emms <simon@simonemms.com>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	"github.com/subosito/gotenv"
+)
+
+// Loads the entries with the prefix from a .env file into the environment so
+// they're passed to the workflows. Real envvars take precedence.
+func loadEnvFile(file, prefix string) error {
+	data, err := os.ReadFile(filepath.Clean(file))
+	if err != nil {
+		return fmt.Errorf("error reading env file: %w", err)
+	}
+
+	env, err := gotenv.StrictParse(bytes.NewReader(data))
+	if err != nil {
+		// Parse line-by-line so only the malformed lines are skipped
+		env = gotenv.Env{}
+		scanner := bufio.NewScanner(bytes.NewReader(data))
+		for line := 1; scanner.Scan(); line++ {
+			text := strings.TrimSpace(scanner.Text())
+			if text == "" || strings.HasPrefix(text, "#") {
+				continue
+			}
+
+			e, err := gotenv.StrictParse(strings.NewReader(text))
+			if err != nil {
+				log.Warn().Str("file", file).Int("line", line).Err(err).Msg("Ignoring malformed line in env file")
+				continue
+			}
+			maps.Copy(env, e)
+		}
+	}
+
+	for k, v := range env {
+		if !strings.HasPrefix(k, prefix) {
+			log.Debug().Str("key", k).Msg("Ignoring env file entry without prefix")
+			continue
+		}
+		if _, ok := os.LookupEnv(k); ok {
+			log.Debug().Str("key", k).Msg("Env file entry already set in environment")
+			continue
+		}
+		if err := os.Setenv(k, v); err != nil {
+			return fmt.Errorf("error setting envvar %s: %w", k, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,45 @@
+/*
+ * Copyright 2025 Simon Emms <simon@simonemms.com>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import "go.temporal.io/sdk/interceptor"
+
+var (
+	clientInterceptors []interceptor.ClientInterceptor
+	workerInterceptors []interceptor.WorkerInterceptor
+)
+
+// RegisterClientInterceptors adds interceptors to the Temporal client, such as
+// to refresh auth or audit the workflows started. These must be registered
+// before Execute is called.
+//
+// Interceptors run in the order they're registered, with the first being the
+// outermost. A client interceptor that's also a worker interceptor is added to
+// the worker too, before any registered with RegisterWorkerInterceptors.
+func RegisterClientInterceptors(i ...interceptor.ClientInterceptor) {
+	clientInterceptors = append(clientInterceptors, i...)
+}
+
+// RegisterWorkerInterceptors adds interceptors to the worker's workflows and
+// activities, such as for custom metrics. These must be registered before
+// Execute is called, and are kept when the worker is reloaded.
+//
+// Interceptors run in the order they're registered, with the first being the
+// outermost.
+func RegisterWorkerInterceptors(i ...interceptor.WorkerInterceptor) {
+	workerInterceptors = append(workerInterceptors, i...)
+}
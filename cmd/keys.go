@@ -0,0 +1,133 @@
+/*
+ * Copyright 2025 Simon Emms <simon@simonemms.com>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/mrsimonemms/temporal-codec-server/packages/golang/algorithms/aes"
+	"github.com/rs/zerolog/log"
+	commonpb "go.temporal.io/api/common/v1"
+	"go.temporal.io/sdk/converter"
+)
+
+// An AES payload codec that reloads its keys from the file, so rotated keys
+// are used without a restart. New data is encrypted with the active key, or
+// the first key if that's not set, and data is decrypted with the key ID in
+// its metadata, so old keys must be kept in the file while there's still data
+// encrypted with them.
+type reloadingCodec struct {
+	activeKey string
+	file      string
+	mu        sync.RWMutex
+	keys      aes.Keys
+	codec     converter.PayloadCodec
+}
+
+// Loads the keys and, with a non-zero interval, reloads them in the background
+func newReloadingCodec(file, activeKey string, interval time.Duration) (*reloadingCodec, error) {
+	c := &reloadingCodec{
+		activeKey: activeKey,
+		file:      file,
+	}
+	if err := c.reload(); err != nil {
+		return nil, err
+	}
+
+	if interval > 0 {
+		log.Debug().Str("file", file).Dur("interval", interval).Msg("Reloading converter keys periodically")
+
+		go func() {
+			ticker := time.NewTicker(interval)
+			defer ticker.Stop()
+
+			for range ticker.C {
+				if err := c.reload(); err != nil {
+					// Keep the existing keys so data can still be converted
+					log.Error().Err(err).Str("file", file).Msg("Error reloading converter keys - keeping existing keys")
+				}
+			}
+		}()
+	}
+
+	return c, nil
+}
+
+func (c *reloadingCodec) reload() error {
+	keys, err := aes.ReadKeyFile(c.file)
+	if err != nil {
+		return fmt.Errorf("unable to get keys from file %s: %w", c.file, err)
+	}
+	if keys, err = withActiveKey(keys, c.activeKey); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.codec != nil && slices.Equal(keys, c.keys) {
+		return nil
+	}
+	if c.codec != nil {
+		log.Info().Str("file", c.file).Str("encryptionKey", keys[0].ID).Msg("Converter keys reloaded")
+	}
+
+	c.keys = keys
+	c.codec = aes.NewPayloadCodec(keys)
+
+	return nil
+}
+
+// The codec encrypts with the first key, so the active key is moved to the front
+func withActiveKey(keys aes.Keys, id string) (aes.Keys, error) {
+	if id == "" {
+		return keys, nil
+	}
+
+	i := slices.IndexFunc(keys, func(k aes.Key) bool {
+		return k.ID == id
+	})
+	if i == -1 {
+		return nil, fmt.Errorf("unknown converter key id: %s", id)
+	}
+
+	active := make(aes.Keys, 0, len(keys))
+	active = append(active, keys[i])
+	active = append(active, slices.Delete(slices.Clone(keys), i, i+1)...)
+
+	return active, nil
+}
+
+func (c *reloadingCodec) current() converter.PayloadCodec {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.codec
+}
+
+// Encode implements converter.PayloadCodec
+func (c *reloadingCodec) Encode(payloads []*commonpb.Payload) ([]*commonpb.Payload, error) {
+	return c.current().Encode(payloads)
+}
+
+// Decode implements converter.PayloadCodec
+func (c *reloadingCodec) Decode(payloads []*commonpb.Payload) ([]*commonpb.Payload, error) {
+	return c.current().Decode(payloads)
+}
@@ -16,36 +16,61 @@ limitations under the License.
 package cmd
 
 import (
-	"crypto/tls"
 	"fmt"
 	"os"
 	"strings"
+	"time"
 
-	"github.com/mrsimonemms/golang-helpers/temporal"
-	"github.com/mrsimonemms/temporal-codec-server/packages/golang/algorithms/aes"
-	tsw "github.com/mrsimonemms/temporal-serverless-workflow/pkg/workflow"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 	"go.temporal.io/sdk/client"
-	"go.temporal.io/sdk/converter"
 	"go.temporal.io/sdk/worker"
-	"go.temporal.io/sdk/workflow"
 )
 
 var rootOpts struct {
-	ConvertData        bool
-	ConvertKeyPath     string
-	EnvPrefix          string
-	FilePath           string
-	LogLevel           string
-	TaskQueue          string
-	TemporalAddress    string
-	TemporalAPIKey     string
-	TemporalTLSEnabled bool
-	TemporalNamespace  string
-	Validate           bool
+	ActivityOptionsPath string
+	AllowUnsupported    []string
+	CompleteSignal      bool
+	ConvertData         bool
+	ConvertKeyID        string
+	ConvertKeyPath      string
+	ConvertKeyReload    time.Duration
+	EmptyResponse       string
+	EnvFile             string
+	EnvPrefix           string
+	ExactNumbers        bool
+	FilePath            string
+	HTTPDebug           bool
+	HTTPDebugFile       string
+	HTTPDebugSize       int
+	HTTPDryRun          bool
+	HTTPErrorBodySize   int
+	HTTPTaskQueuesPath  string
+	LogLevel            string
+	MaxTasks            int
+	NameSuffix          string
+	OutputViewsPath     string
+	PriorityKey         int
+	PropagateVariables  []string
+	Reload              bool
+	StickyCacheSize     int
+	StickyTimeout       time.Duration
+	TagsSearchAttribute string
+	Timeline            bool
+	TaskQueue           string
+	TemporalAddress     string
+	TemporalAPIKey      string
+	TemporalAPIKeyFile  string
+	TemporalDialTimeout time.Duration
+	TemporalTLSEnabled  bool
+	TemporalNamespace   string
+	Validate            bool
+	Watch               bool
+	WorkerIdentity      string
+	WorkflowIDPrefix    string
+	WorkflowRetryPath   string
 }
 
 // rootCmd represents the base command when called without any subcommands
@@ -63,84 +88,75 @@ var rootCmd = &cobra.Command{
 		return nil
 	},
 	PreRun: func(cmd *cobra.Command, args []string) {
-		if rootOpts.EnvPrefix == "" {
-			log.Fatal().Str("prefix", rootOpts.EnvPrefix).Msg("Env prefix cannot be empty")
-		}
-		if strings.HasSuffix(rootOpts.EnvPrefix, "_") {
-			log.Fatal().Str("prefix", rootOpts.EnvPrefix).Msg("Env prefix cannot end with underscore (_)")
-		}
+		validateWorkerOpts()
 	},
 	Run: func(cmd *cobra.Command, args []string) {
-		connectionOpts := client.ConnectionOptions{}
-		if rootOpts.TemporalTLSEnabled {
-			// Use new to avoid a golint false positive
-			log.Debug().Msg("Enabling TLS connection")
-			connectionOpts.TLS = new(tls.Config)
-		}
-		var creds client.Credentials
-		if rootOpts.TemporalAPIKey != "" {
-			log.Debug().Msg("Using API key for authentcation")
-			creds = client.NewAPIKeyStaticCredentials(rootOpts.TemporalAPIKey)
-		}
+		runWorker()
+	},
+}
 
-		var converter converter.DataConverter
-		if rootOpts.ConvertData {
-			keys, err := aes.ReadKeyFile(rootOpts.ConvertKeyPath)
-			if err != nil {
-				log.Fatal().Err(err).Str("keypath", rootOpts.ConvertKeyPath).Msg("Unable to get keys from file")
-			}
-			converter = aes.DataConverter(keys)
-		}
+// Checks the options used by the worker
+func validateWorkerOpts() {
+	if rootOpts.EnvPrefix == "" {
+		log.Fatal().Str("prefix", rootOpts.EnvPrefix).Msg("Env prefix cannot be empty")
+	}
+	if strings.HasSuffix(rootOpts.EnvPrefix, "_") {
+		log.Fatal().Str("prefix", rootOpts.EnvPrefix).Msg("Env prefix cannot end with underscore (_)")
+	}
+}
 
-		// The client and worker are heavyweight objects that should be created once per process.
-		c, err := client.Dial(client.Options{
-			ConnectionOptions: connectionOpts,
-			Credentials:       creds,
-			HostPort:          rootOpts.TemporalAddress,
-			Namespace:         rootOpts.TemporalNamespace,
-			DataConverter:     converter,
-			Logger:            temporal.NewZerologHandler(&log.Logger),
-		})
-		if err != nil {
-			log.Fatal().Err(err).Msg("Unable to create client")
-		}
-		defer c.Close()
+// Runs the worker until it's interrupted
+func runWorker() {
+	// The client and worker are heavyweight objects that should be created once per process.
+	if rootOpts.WorkerIdentity == "" {
+		rootOpts.WorkerIdentity = defaultWorkerIdentity()
+	}
+	log.Info().Str("identity", rootOpts.WorkerIdentity).Msg("Worker identity")
 
-		// Load the workflow file
-		wf, err := tsw.LoadFromFile(rootOpts.FilePath, rootOpts.EnvPrefix)
-		if err != nil {
-			log.Fatal().Err(err).Msg("Error loading workflow")
+	if rootOpts.EnvFile != "" {
+		log.Debug().Str("file", rootOpts.EnvFile).Msg("Loading env file")
+		if err := loadEnvFile(rootOpts.EnvFile, rootOpts.EnvPrefix); err != nil {
+			log.Fatal().Err(err).Msg("Error loading env file")
 		}
+	}
 
-		if rootOpts.Validate {
-			log.Debug().Msg("Running validation")
-			if err := wf.Validate(); err != nil {
-				log.Fatal().Err(err).Msg("Failed validation")
-			}
-		}
+	c, err := newClient(rootOpts.WorkerIdentity)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Unable to create client")
+	}
+	defer c.Close()
 
-		w := worker.New(c, rootOpts.TaskQueue, worker.Options{})
+	if rootOpts.HTTPDryRun {
+		log.Warn().Msg("HTTP DRY RUN ENABLED - NO HTTP CALLS WILL BE SENT. DO NOT USE IN PRODUCTION")
+	}
+	if rootOpts.HTTPDebug {
+		log.Warn().Msg("HTTP debugging enabled - requests and responses will be recorded")
+	}
 
-		workflows, err := wf.BuildWorkflows()
-		if err != nil {
-			log.Fatal().Err(err).Msg("Error building workflows")
-		}
+	if rootOpts.StickyCacheSize > 0 {
+		// This is global so must be set before any workers are created
+		log.Debug().Int("size", rootOpts.StickyCacheSize).Msg("Setting sticky workflow cache size")
+		worker.SetStickyWorkflowCacheSize(rootOpts.StickyCacheSize)
+	}
 
-		for _, wf := range workflows {
-			log.Debug().Str("name", wf.Name).Msg("Registering workflow")
-			w.RegisterWorkflowWithOptions(wf.Workflow, workflow.RegisterOptions{
-				Name: wf.Name,
-			})
-		}
+	w, definitions, err := newWorker(c)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Error creating worker")
+	}
 
-		log.Debug().Msg("Registering activities")
-		w.RegisterActivity(wf.Activities())
+	if rootOpts.Watch && !isDevelopmentBuild() {
+		log.Warn().Str("version", Version).Msg("Watch mode is for development only - ignoring --watch")
+		rootOpts.Watch = false
+	}
 
+	if rootOpts.Reload || rootOpts.Watch {
+		err = runReloadableWorker(c, w, definitions)
+	} else {
 		err = w.Run(worker.InterruptCh())
-		if err != nil {
-			log.Fatal().Err(err).Msg("Unable to start worker")
-		}
-	},
+	}
+	if err != nil {
+		log.Fatal().Err(err).Msg("Unable to start worker")
+	}
 }
 
 // Execute adds all child commands to the root command and sets flags appropriately.
@@ -155,21 +171,63 @@ func Execute() {
 func init() {
 	viper.AutomaticEnv()
 
+	rootCmd.Flags().StringVar(
+		&rootOpts.ActivityOptionsPath,
+		"activity-options",
+		viper.GetString("activity_options"),
+		"Path to default activity options for each task type",
+	)
+
+	rootCmd.Flags().StringSliceVar(
+		&rootOpts.AllowUnsupported,
+		"allow-unsupported",
+		viper.GetStringSlice("allow_unsupported"),
+		"Unsupported tasks to allow through validation, eg switch,try - these are experimental and won't be run",
+	)
+
 	rootCmd.Flags().BoolVar(
+		&rootOpts.CompleteSignal,
+		"complete-signal",
+		viper.GetBool("complete_signal"),
+		"Allow workflows to be completed early with the __complete signal",
+	)
+
+	rootCmd.PersistentFlags().BoolVar(
 		&rootOpts.ConvertData,
 		"convert-data",
 		viper.GetBool("convert_data"),
 		"Enable AES data conversion",
 	)
 
+	rootCmd.PersistentFlags().BoolVar(
+		&rootOpts.ExactNumbers,
+		"exact-numbers",
+		viper.GetBool("exact_numbers"),
+		"Decode JSON numbers exactly rather than as floats - use on the worker and the clients starting workflows",
+	)
+
+	rootCmd.PersistentFlags().StringVar(
+		&rootOpts.ConvertKeyID,
+		"converter-key-id",
+		viper.GetString("converter_key_id"),
+		"ID of the AES key new data is encrypted with - the first key in the file if not set",
+	)
+
 	viper.SetDefault("converter_key_path", "keys.yaml")
-	rootCmd.Flags().StringVar(
+	rootCmd.PersistentFlags().StringVar(
 		&rootOpts.ConvertKeyPath,
 		"converter-key-path",
 		viper.GetString("converter_key_path"),
 		"Path to AES conversion keys",
 	)
 
+	rootCmd.PersistentFlags().DurationVar(
+		&rootOpts.ConvertKeyReload,
+		"converter-key-reload-interval",
+		viper.GetDuration("converter_key_reload_interval"),
+		"How often to reload the AES conversion keys, so rotated keys are used without a restart - 0 disables",
+	)
+
 	rootCmd.Flags().StringVarP(
 		&rootOpts.FilePath,
 		"file",
@@ -178,6 +236,21 @@ func init() {
 		"Path to workflow file",
 	)
 
+	viper.SetDefault("empty_response", "null")
+	rootCmd.Flags().StringVar(
+		&rootOpts.EmptyResponse,
+		"empty-response",
+		viper.GetString("empty_response"),
+		"How an HTTP call's empty response body is returned in bodyJSON - null or object",
+	)
+
+	rootCmd.Flags().StringVar(
+		&rootOpts.EnvFile,
+		"env-file",
+		viper.GetString("env_file"),
+		"Path to a .env file - entries with the env prefix are loaded to the workflow, under any envvars",
+	)
+
 	viper.SetDefault("env_prefix", "TSW")
 	rootCmd.Flags().StringVar(
 		&rootOpts.EnvPrefix,
@@ -186,6 +259,50 @@ func init() {
 		"Load envvars with this prefix to the workflow",
 	)
 
+	rootCmd.Flags().BoolVar(
+		&rootOpts.HTTPDebug,
+		"http-debug",
+		viper.GetBool("http_debug"),
+		"Record HTTP requests and responses, with secrets redacted",
+	)
+
+	rootCmd.Flags().StringVar(
+		&rootOpts.HTTPDebugFile,
+		"http-debug-file",
+		viper.GetString("http_debug_file"),
+		"Append recorded HTTP requests and responses to this file",
+	)
+
+	viper.SetDefault("http_debug_size", 50)
+	rootCmd.Flags().IntVar(
+		&rootOpts.HTTPDebugSize,
+		"http-debug-size",
+		viper.GetInt("http_debug_size"),
+		"Number of recorded HTTP requests and responses kept in the workflow state query",
+	)
+
+	rootCmd.Flags().BoolVar(
+		&rootOpts.HTTPDryRun,
+		"http-dry-run",
+		viper.GetBool("http_dry_run"),
+		"Log HTTP calls rather than sending them - for development only",
+	)
+
+	viper.SetDefault("http_error_body_size", 4096)
+	rootCmd.Flags().IntVar(
+		&rootOpts.HTTPErrorBodySize,
+		"http-error-body-size",
+		viper.GetInt("http_error_body_size"),
+		"Bytes of a failed HTTP call's response body attached to its error - 0 is unlimited",
+	)
+
+	rootCmd.Flags().StringVar(
+		&rootOpts.HTTPTaskQueuesPath,
+		"http-task-queues",
+		viper.GetString("http_task_queues"),
+		"Path to a map of hostnames to the task queue their HTTP calls are run on",
+	)
+
 	viper.SetDefault("log_level", zerolog.InfoLevel.String())
 	rootCmd.PersistentFlags().StringVarP(
 		&rootOpts.LogLevel,
@@ -195,8 +312,86 @@ func init() {
 		fmt.Sprintf("log level: %s", "Set log level"),
 	)
 
+	viper.SetDefault("max_tasks", 10000)
+	rootCmd.Flags().IntVar(
+		&rootOpts.MaxTasks,
+		"max-tasks",
+		viper.GetInt("max_tasks"),
+		"Number of tasks a workflow can run before it's failed, to stop runaway loops - 0 is unlimited",
+	)
+
+	rootCmd.Flags().StringVar(
+		&rootOpts.NameSuffix,
+		"name-suffix",
+		viper.GetString("name_suffix"),
+		"Suffix added to the registered workflow names, eg staging registers order-process as order-process-staging",
+	)
+
+	rootCmd.Flags().StringVar(
+		&rootOpts.OutputViewsPath,
+		"output-views",
+		viper.GetString("output_views"),
+		"Path to named jq expressions the output can be returned as, chosen with start --output-view",
+	)
+
+	rootCmd.Flags().IntVar(
+		&rootOpts.PriorityKey,
+		"priority-key",
+		viper.GetInt("priority_key"),
+		"Default priority key for workflows and activities - lower is scheduled first. 0 is unset",
+	)
+
+	rootCmd.PersistentFlags().StringSliceVar(
+		&rootOpts.PropagateVariables,
+		"propagate-variables",
+		viper.GetStringSlice("propagate_variables"),
+		"Variables to propagate across workflows in Temporal headers, eg correlationId,tenant",
+	)
+
+	rootCmd.Flags().BoolVar(
+		&rootOpts.Reload,
+		"reload",
+		viper.GetBool("reload"),
+		"Reload the workflow file on SIGHUP",
+	)
+
+	rootCmd.Flags().BoolVar(
+		&rootOpts.Watch,
+		"watch",
+		viper.GetBool("watch"),
+		"Watch the workflow file and reload on changes - development builds only",
+	)
+
+	rootCmd.Flags().IntVar(
+		&rootOpts.StickyCacheSize,
+		"sticky-cache-size",
+		viper.GetInt("sticky_cache_size"),
+		"Number of workflows kept in the sticky cache - 0 uses the SDK default (10000)",
+	)
+
+	rootCmd.Flags().DurationVar(
+		&rootOpts.StickyTimeout,
+		"sticky-schedule-to-start-timeout",
+		viper.GetDuration("sticky_schedule_to_start_timeout"),
+		"How long a sticky workflow task waits for this worker before another picks it up - 0 uses the SDK default (5s)",
+	)
+
+	rootCmd.Flags().StringVar(
+		&rootOpts.TagsSearchAttribute,
+		"tags-search-attribute",
+		viper.GetString("tags_search_attribute"),
+		"Keyword list search attribute to upsert the document tags to - this must be registered in the namespace",
+	)
+
+	rootCmd.Flags().BoolVar(
+		&rootOpts.Timeline,
+		"timeline",
+		viper.GetBool("timeline"),
+		"Record a timeline of the tasks run in the workflow's output and the __timeline query - this increases the output size",
+	)
+
 	viper.SetDefault("task_queue", "serverless-workflow")
-	rootCmd.Flags().StringVarP(
+	rootCmd.PersistentFlags().StringVarP(
 		&rootOpts.TaskQueue,
 		"task-queue",
 		"q",
@@ -205,7 +400,7 @@ func init() {
 	)
 
 	viper.SetDefault("temporal_address", client.DefaultHostPort)
-	rootCmd.Flags().StringVarP(
+	rootCmd.PersistentFlags().StringVarP(
 		&rootOpts.TemporalAddress,
 		"temporal-address",
 		"H",
@@ -213,20 +408,34 @@ func init() {
 		"Address of the Temporal server",
 	)
 
-	rootCmd.Flags().StringVar(
+	rootCmd.PersistentFlags().StringVar(
 		&rootOpts.TemporalAPIKey,
 		"temporal-api-key",
 		viper.GetString("temporal_api_key"),
 		"API key for Temporal authentication",
 	)
 	// Hide the default value to avoid spaffing the API to command line
-	apiKey := rootCmd.Flags().Lookup("temporal-api-key")
+	apiKey := rootCmd.PersistentFlags().Lookup("temporal-api-key")
 	if s := apiKey.Value; s.String() != "" {
 		apiKey.DefValue = "***"
 	}
 
+	rootCmd.PersistentFlags().StringVar(
+		&rootOpts.TemporalAPIKeyFile,
+		"temporal-api-key-file",
+		viper.GetString("temporal_api_key_file"),
+		"Path to file containing the API key for Temporal authentication - takes precedence over --temporal-api-key",
+	)
+
+	rootCmd.PersistentFlags().DurationVar(
+		&rootOpts.TemporalDialTimeout,
+		"temporal-dial-timeout",
+		viper.GetDuration("temporal_dial_timeout"),
+		"How long to retry connecting to Temporal at startup - 0 only tries once",
+	)
+
 	viper.SetDefault("temporal_namespace", client.DefaultNamespace)
-	rootCmd.Flags().StringVarP(
+	rootCmd.PersistentFlags().StringVarP(
 		&rootOpts.TemporalNamespace,
 		"temporal-namespace",
 		"n",
@@ -235,13 +444,34 @@ func init() {
 	)
 
 	viper.SetDefault("temporal_tls", client.DefaultNamespace)
-	rootCmd.Flags().BoolVar(
+	rootCmd.PersistentFlags().BoolVar(
 		&rootOpts.TemporalTLSEnabled,
 		"temporal-tls",
 		viper.GetBool("temporal_tls"),
 		"Enable TLS Temporal connection",
 	)
 
+	rootCmd.PersistentFlags().StringVar(
+		&rootOpts.WorkflowIDPrefix,
+		"workflow-id-prefix",
+		viper.GetString("workflow_id_prefix"),
+		"Prefix applied to generated workflow names and IDs, such as a tenant",
+	)
+
+	rootCmd.Flags().StringVar(
+		&rootOpts.WorkerIdentity,
+		"worker-identity",
+		viper.GetString("worker_identity"),
+		"Identity of the worker shown in Temporal - defaults to hostname@version@task-queue",
+	)
+
+	rootCmd.Flags().StringVar(
+		&rootOpts.WorkflowRetryPath,
+		"workflow-retry-options",
+		viper.GetString("workflow_retry_options"),
+		"Path to a YAML file of the default retry policy for whole workflow runs",
+	)
+
 	viper.SetDefault("validate", true)
 	rootCmd.Flags().BoolVar(
 		&rootOpts.Validate,
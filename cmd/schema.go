@@ -0,0 +1,43 @@
+/*
+ * Copyright 2025 Simon Emms <simon@simonemms.com>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"encoding/json"
+	"os"
+
+	tsw "github.com/mrsimonemms/temporal-serverless-workflow/pkg/workflow"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+// schemaCmd represents the schema command
+var schemaCmd = &cobra.Command{
+	Use:   "schema",
+	Short: "Displays the supported Serverless Workflow tasks as JSON",
+	Run: func(cmd *cobra.Command, args []string) {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(tsw.SupportedSchema()); err != nil {
+			log.Fatal().Err(err).Msg("Unable to output schema")
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(schemaCmd)
+}
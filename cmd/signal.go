@@ -0,0 +1,91 @@
+/*
+ * Copyright 2025 Simon Emms <simon@simonemms.com>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	tsw "github.com/mrsimonemms/temporal-serverless-workflow/pkg/workflow"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var signalOpts struct {
+	Input string
+	RunID string
+}
+
+// signalCmd represents the signal command
+var signalCmd = &cobra.Command{
+	Use:   "signal <workflow-id> <signal>",
+	Short: "Sends a signal to a running workflow",
+	Long: fmt.Sprintf(`Sends a signal to a running workflow. As well as any signals the workflow
+listens for, the built-in signals are:
+
+  %s: pause the workflow before its next task
+  %s: resume a paused workflow
+  %s: complete the workflow early, if the worker allows it`, tsw.PauseSignal, tsw.ResumeSignal, tsw.CompleteSignal),
+	Args: cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx := context.Background()
+
+		var input any
+		if signalOpts.Input != "" {
+			// Keep large integers exact rather than rounding them to a float
+			decoder := json.NewDecoder(strings.NewReader(signalOpts.Input))
+			decoder.UseNumber()
+			if err := decoder.Decode(&input); err != nil {
+				log.Fatal().Err(err).Msg("Input must be json")
+			}
+		}
+
+		c, err := newClient("")
+		if err != nil {
+			log.Fatal().Err(err).Msg("Unable to create client")
+		}
+		defer c.Close()
+
+		if err := c.SignalWorkflow(ctx, args[0], signalOpts.RunID, args[1], input); err != nil {
+			c.Close()
+			log.Fatal().Err(err).Str("workflowId", args[0]).Str("signal", args[1]).Msg("Error sending signal")
+		}
+
+		log.Info().Str("workflowId", args[0]).Str("signal", args[1]).Msg("Signal sent")
+	},
+}
+
+func init() {
+	signalCmd.Flags().StringVar(
+		&signalOpts.Input,
+		"input",
+		viper.GetString("input"),
+		"JSON payload to send with the signal",
+	)
+
+	signalCmd.Flags().StringVar(
+		&signalOpts.RunID,
+		"run-id",
+		viper.GetString("run_id"),
+		"Run ID of the workflow - the latest run if not set",
+	)
+
+	rootCmd.AddCommand(signalCmd)
+}
@@ -0,0 +1,400 @@
+/*
+ * Copyright 2025 Simon Emms <simon@simonemms.com>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/google/uuid"
+	tsw "github.com/mrsimonemms/temporal-serverless-workflow/pkg/workflow"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"go.temporal.io/api/enums/v1"
+	"go.temporal.io/sdk/client"
+	"gopkg.in/yaml.v3"
+)
+
+var startOpts struct {
+	Auth              tsw.AuthConfig
+	AuthToken         string
+	BusinessKey       string
+	BusinessKeyPolicy string
+	Input             string
+	InputFile         string
+	MaxInputSize      int
+	OffloadDir        string
+	Output            string
+	OutputView        string
+	SkipTasks         []string
+	StartFrom         string
+	Wait              bool
+	WorkflowID        string
+}
+
+// Writes the workflow result to stdout so it can be piped
+func writeResult(result any, format string) error {
+	switch format {
+	case "json":
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(result)
+	case "yaml":
+		enc := yaml.NewEncoder(os.Stdout)
+		enc.SetIndent(2)
+		if err := enc.Encode(result); err != nil {
+			return err
+		}
+		return enc.Close()
+	default:
+		return fmt.Errorf("unknown output format: %s", format)
+	}
+}
+
+// Builds the workflow ID from the business key, if set. This is done before
+// the input is offloaded so the expression can use any of the input. Generated
+// IDs have the workflow ID prefix, but a given ID is used as-is.
+func startWorkflowID(name string, input tsw.HTTPData) (string, error) {
+	if startOpts.BusinessKey == "" {
+		if startOpts.WorkflowID == "" && rootOpts.WorkflowIDPrefix != "" {
+			return tsw.PrefixWorkflowID(rootOpts.WorkflowIDPrefix, uuid.NewString()), nil
+		}
+		return startOpts.WorkflowID, nil
+	}
+	if startOpts.WorkflowID != "" {
+		return "", fmt.Errorf("%w: can't be used with a workflow id", tsw.ErrInvalidBusinessKey)
+	}
+
+	id, err := tsw.BusinessKeyWorkflowID(name, startOpts.BusinessKey, input)
+	if err != nil {
+		return "", err
+	}
+
+	return tsw.PrefixWorkflowID(rootOpts.WorkflowIDPrefix, id), nil
+}
+
+// Builds the workflow input, checking it's not too large to send
+func startInput(ctx context.Context, name string) (tsw.HTTPData, string, error) {
+	input := tsw.HTTPData{}
+	if startOpts.Input != "" {
+		// Keep large integers exact rather than rounding them to a float
+		decoder := json.NewDecoder(strings.NewReader(startOpts.Input))
+		decoder.UseNumber()
+		if err := decoder.Decode(&input); err != nil {
+			return nil, "", fmt.Errorf("input must be a json object: %w", err)
+		}
+	}
+
+	// The claims can only come from a verified token
+	for _, key := range []string{tsw.AuthKey, tsw.InputFileKey} {
+		if _, ok := input[key]; ok {
+			return nil, "", fmt.Errorf("%w: %s", tsw.ErrReservedInputKey, key)
+		}
+	}
+	if err := tsw.CheckReservedKeys(input); err != nil {
+		return nil, "", err
+	}
+
+	if startOpts.InputFile != "" {
+		data, err := os.ReadFile(filepath.Clean(startOpts.InputFile))
+		if err != nil {
+			return nil, "", fmt.Errorf("error reading input file: %w", err)
+		}
+		input[tsw.InputFileKey] = base64.StdEncoding.EncodeToString(data)
+	}
+
+	// Resume a partially successful workflow
+	if len(startOpts.SkipTasks) > 0 {
+		input[tsw.SkipTasksKey] = startOpts.SkipTasks
+	}
+	if startOpts.StartFrom != "" {
+		input[tsw.StartFromKey] = startOpts.StartFrom
+	}
+
+	if startOpts.OutputView != "" {
+		input[tsw.OutputViewKey] = startOpts.OutputView
+	}
+
+	if startOpts.AuthToken != "" || startOpts.Auth.JWKSURL != "" || startOpts.Auth.KeyFile != "" {
+		if startOpts.AuthToken == "" {
+			return nil, "", fmt.Errorf("%w: token is required", tsw.ErrInvalidToken)
+		}
+
+		auth, err := tsw.AuthInput(ctx, startOpts.AuthToken, startOpts.Auth)
+		if err != nil {
+			return nil, "", err
+		}
+		maps.Copy(input, auth)
+	}
+
+	id, err := startWorkflowID(name, input)
+	if err != nil {
+		return nil, "", err
+	}
+
+	err = tsw.CheckInputSize(input, startOpts.MaxInputSize)
+	if errors.Is(err, tsw.ErrInputTooLarge) && startOpts.OffloadDir != "" {
+		log.Info().Str("dir", startOpts.OffloadDir).Msg("Input too large - offloading")
+		input, err = tsw.OffloadInput(input, startOpts.OffloadDir)
+		return input, id, err
+	}
+	if err != nil {
+		return nil, "", err
+	}
+
+	return input, id, nil
+}
+
+// Builds the options to start the workflow with. If the workflow file is set,
+// the workflow's retry policy and priority are applied, as they can't be
+// changed once started.
+func startWorkflowOptions(id string, conflictPolicy enums.WorkflowIdConflictPolicy) (client.StartWorkflowOptions, error) {
+	opts := client.StartWorkflowOptions{
+		TaskQueue: rootOpts.TaskQueue,
+	}
+	if rootOpts.FilePath != "" {
+		wfOpts, err := workflowOptions()
+		if err != nil {
+			return opts, err
+		}
+
+		wf, err := tsw.LoadFromFile(rootOpts.FilePath, rootOpts.EnvPrefix, wfOpts...)
+		if err != nil {
+			return opts, fmt.Errorf("error loading workflow: %w", err)
+		}
+
+		opts, err = wf.StartWorkflowOptions(rootOpts.TaskQueue)
+		if err != nil {
+			return opts, err
+		}
+	}
+
+	opts.ID = id
+	opts.WorkflowIDConflictPolicy = conflictPolicy
+
+	return opts, nil
+}
+
+// startCmd represents the start command
+var startCmd = &cobra.Command{
+	Use:   "start <workflow>",
+	Short: "Starts a workflow",
+	Args:  cobra.ExactArgs(1),
+	PreRun: func(cmd *cobra.Command, args []string) {
+		if startOpts.Output != "json" && startOpts.Output != "yaml" {
+			log.Fatal().Str("output", startOpts.Output).Msg("Output must be json or yaml")
+		}
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx := context.Background()
+
+		input, id, err := startInput(ctx, args[0])
+		if err != nil {
+			log.Fatal().Err(err).Msg("Invalid workflow input")
+		}
+
+		conflictPolicy, err := tsw.BusinessKeyPolicy(startOpts.BusinessKeyPolicy).ConflictPolicy()
+		if err != nil {
+			log.Fatal().Err(err).Msg("Invalid business key policy")
+		}
+
+		opts, err := startWorkflowOptions(id, conflictPolicy)
+		if err != nil {
+			log.Fatal().Err(err).Msg("Invalid workflow options")
+		}
+
+		c, err := newClient("")
+		if err != nil {
+			log.Fatal().Err(err).Msg("Unable to create client")
+		}
+		defer c.Close()
+
+		// Any propagated variables in the input are also sent as headers
+		we, err := c.ExecuteWorkflow(tsw.WithPropagatedValues(ctx, input), opts, args[0], input)
+		if err != nil {
+			log.Fatal().Err(err).Msg("Error starting workflow")
+		}
+
+		log.Info().Str("workflowId", we.GetID()).Str("runId", we.GetRunID()).Msg("Started workflow")
+
+		if !startOpts.Wait {
+			return
+		}
+
+		// The client's data converter decodes the result
+		var result any
+		if err := we.Get(ctx, &result); err != nil {
+			c.Close()
+			log.Fatal().Err(err).Str("workflowId", we.GetID()).Msg("Workflow failed")
+		}
+
+		if err := writeResult(result, startOpts.Output); err != nil {
+			c.Close()
+			log.Fatal().Err(err).Msg("Unable to output workflow result")
+		}
+	},
+}
+
+func init() {
+	startCmd.Flags().StringVar(
+		&startOpts.Auth.Audience,
+		"auth-audience",
+		viper.GetString("auth_audience"),
+		"If set, the token's audience must include this",
+	)
+
+	startCmd.Flags().StringSliceVar(
+		&startOpts.Auth.Claims,
+		"auth-claims",
+		viper.GetStringSlice("auth_claims"),
+		"Claims to pass to the workflow - all claims if not set",
+	)
+
+	startCmd.Flags().StringVar(
+		&startOpts.Auth.Issuer,
+		"auth-issuer",
+		viper.GetString("auth_issuer"),
+		"If set, the token's issuer must match this",
+	)
+
+	startCmd.Flags().StringVar(
+		&startOpts.Auth.JWKSURL,
+		"auth-jwks-url",
+		viper.GetString("auth_jwks_url"),
+		"URL of the JSON Web Key Set to verify the token",
+	)
+
+	startCmd.Flags().StringVar(
+		&startOpts.Auth.KeyFile,
+		"auth-key-file",
+		viper.GetString("auth_key_file"),
+		"Path to a PEM public key or certificate, or an HMAC secret, to verify the token",
+	)
+
+	startCmd.Flags().StringVar(
+		&startOpts.AuthToken,
+		"auth-token",
+		viper.GetString("auth_token"),
+		"JWT of the caller - the verified claims are passed to the workflow",
+	)
+
+	startCmd.Flags().StringVar(
+		&startOpts.BusinessKey,
+		"business-key",
+		viper.GetString("business_key"),
+		"jq expression run against the input to build the workflow id, eg ${ .orderId } - only one workflow can run per key",
+	)
+
+	viper.SetDefault("business_key_policy", string(tsw.BusinessKeyPolicyReject))
+	startCmd.Flags().StringVar(
+		&startOpts.BusinessKeyPolicy,
+		"business-key-policy",
+		viper.GetString("business_key_policy"),
+		"What to do if a workflow is already running for the id - reject, use-existing or terminate-existing",
+	)
+
+	// The workflow file is read for the options the workflow is started with
+	startCmd.Flags().AddFlag(rootCmd.Flags().Lookup("file"))
+
+	startCmd.Flags().StringVarP(
+		&startOpts.Input,
+		"input",
+		"i",
+		viper.GetString("input"),
+		"Workflow input as a JSON object",
+	)
+
+	startCmd.Flags().StringVar(
+		&startOpts.InputFile,
+		"input-file",
+		viper.GetString("input_file"),
+		"File to pass to the workflow, base64 encoded in the _tsw_input_file variable",
+	)
+
+	viper.SetDefault("max_input_size", tsw.DefaultMaxInputSize)
+	startCmd.Flags().IntVar(
+		&startOpts.MaxInputSize,
+		"max-input-size",
+		viper.GetInt("max_input_size"),
+		"Maximum size of the input in bytes",
+	)
+
+	startCmd.Flags().StringVar(
+		&startOpts.OffloadDir,
+		"offload-dir",
+		viper.GetString("offload_dir"),
+		"Write inputs over the maximum size to this directory and pass a reference instead - must be readable by the workers",
+	)
+
+	viper.SetDefault("output", "json")
+	startCmd.Flags().StringVarP(
+		&startOpts.Output,
+		"output",
+		"o",
+		viper.GetString("output"),
+		"Format of the result with --wait - json or yaml",
+	)
+
+	startCmd.Flags().StringVar(
+		&startOpts.OutputView,
+		"output-view",
+		viper.GetString("output_view"),
+		"Name of the view the workflow's output is returned as, set on the worker with --output-views",
+	)
+
+	startCmd.Flags().AddFlag(rootCmd.Flags().Lookup("priority-key"))
+
+	startCmd.Flags().StringSliceVar(
+		&startOpts.SkipTasks,
+		"skip-tasks",
+		viper.GetStringSlice("skip_tasks"),
+		"Keys of the workflow's tasks to skip, eg those already done",
+	)
+
+	startCmd.Flags().StringVar(
+		&startOpts.StartFrom,
+		"start-from",
+		viper.GetString("start_from"),
+		"Key of the workflow's task to start from, skipping those before it",
+	)
+
+	startCmd.Flags().BoolVar(
+		&startOpts.Wait,
+		"wait",
+		viper.GetBool("wait"),
+		"Wait for the workflow to complete and output the result",
+	)
+
+	startCmd.Flags().StringVar(
+		&startOpts.WorkflowID,
+		"workflow-id",
+		viper.GetString("workflow_id"),
+		"Workflow ID - generated if not set",
+	)
+
+	startCmd.Flags().AddFlag(rootCmd.Flags().Lookup("workflow-retry-options"))
+
+	rootCmd.AddCommand(startCmd)
+}
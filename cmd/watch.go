@@ -0,0 +1,100 @@
+/*
+ * Copyright 2025 Simon Emms <simon@simonemms.com>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/rs/zerolog/log"
+)
+
+// Editors often write a file in several steps (truncate, write, rename), so
+// changes are batched until the file has been quiet for this long
+const watchDebounce = 500 * time.Millisecond
+
+// Watching is a development tool - release builds have the version set at
+// build time
+func isDevelopmentBuild() bool {
+	return Version == "" || Version == "development"
+}
+
+// Watches the workflow file for changes, calling onChange once the writes
+// have settled. The parent directory is watched rather than the file itself
+// so that editors which replace the file on save are still detected.
+func watchWorkflowFile(file string, debounce time.Duration, onChange func()) (stop func(), err error) {
+	file, err = filepath.Abs(file)
+	if err != nil {
+		return nil, fmt.Errorf("error resolving workflow file path: %w", err)
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, fmt.Errorf("error creating file watcher: %w", err)
+	}
+
+	dir := filepath.Dir(file)
+	if err := watcher.Add(dir); err != nil {
+		_ = watcher.Close()
+		return nil, fmt.Errorf("error watching directory %s: %w", dir, err)
+	}
+
+	log.Info().Str("file", file).Msg("Watching workflow file for changes")
+
+	var mu sync.Mutex
+	var timer *time.Timer
+
+	go func() {
+		for {
+			select {
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if filepath.Clean(event.Name) != file || event.Op == fsnotify.Chmod {
+					continue
+				}
+
+				log.Info().Str("file", event.Name).Str("op", event.Op.String()).Msg("Workflow file changed")
+
+				mu.Lock()
+				if timer != nil {
+					timer.Stop()
+				}
+				timer = time.AfterFunc(debounce, onChange)
+				mu.Unlock()
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				log.Error().Err(err).Msg("Error watching workflow file")
+			}
+		}
+	}()
+
+	return func() {
+		mu.Lock()
+		if timer != nil {
+			timer.Stop()
+		}
+		mu.Unlock()
+		_ = watcher.Close()
+	}, nil
+}
@@ -0,0 +1,367 @@
+/*
+ * Copyright 2025 Simon Emms <simon@simonemms.com>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"os"
+	"os/signal"
+	"slices"
+	"syscall"
+	"time"
+
+	tsw "github.com/mrsimonemms/temporal-serverless-workflow/pkg/workflow"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"go.temporal.io/api/workflowservice/v1"
+	"go.temporal.io/sdk/activity"
+	"go.temporal.io/sdk/client"
+	"go.temporal.io/sdk/worker"
+	"go.temporal.io/sdk/workflow"
+)
+
+// The default worker identity shows which host and build is running, which is
+// useful during rolling upgrades
+func defaultWorkerIdentity() string {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "unknown"
+	}
+
+	version := Version
+	if version == "" {
+		version = "development"
+	}
+
+	return fmt.Sprintf("%s@%s@%s", hostname, version, rootOpts.TaskQueue)
+}
+
+// Builds the options for loading the workflow file
+func workflowOptions() ([]tsw.Option, error) {
+	if rootOpts.PriorityKey < 0 {
+		return nil, fmt.Errorf("%w: %d", tsw.ErrInvalidPriorityKey, rootOpts.PriorityKey)
+	}
+
+	if rootOpts.MaxTasks < 0 {
+		return nil, fmt.Errorf("%w: %d", tsw.ErrInvalidMaxTasks, rootOpts.MaxTasks)
+	}
+
+	if rootOpts.HTTPErrorBodySize < 0 {
+		return nil, fmt.Errorf("%w: %d", tsw.ErrInvalidHTTPErrorBodySize, rootOpts.HTTPErrorBodySize)
+	}
+
+	emptyResponse, err := tsw.ParseEmptyResponse(rootOpts.EmptyResponse)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := []tsw.Option{
+		tsw.WithAllowUnsupported(rootOpts.AllowUnsupported),
+		tsw.WithCompleteSignal(rootOpts.CompleteSignal),
+		tsw.WithEmptyResponse(emptyResponse),
+		tsw.WithHTTPDryRun(rootOpts.HTTPDryRun),
+		tsw.WithHTTPErrorBodySize(rootOpts.HTTPErrorBodySize),
+		tsw.WithMaxTasks(rootOpts.MaxTasks),
+		tsw.WithNameSuffix(rootOpts.NameSuffix),
+		tsw.WithPriorityKey(rootOpts.PriorityKey),
+		tsw.WithTagsSearchAttribute(rootOpts.TagsSearchAttribute),
+		tsw.WithTimeline(rootOpts.Timeline),
+		tsw.WithWorkerIdentity(rootOpts.WorkerIdentity),
+		tsw.WithWorkflowIDPrefix(rootOpts.WorkflowIDPrefix),
+	}
+	if rootOpts.HTTPDebug {
+		opts = append(opts, tsw.WithHTTPDebug(rootOpts.HTTPDebugSize, rootOpts.HTTPDebugFile))
+	}
+	if rootOpts.ActivityOptionsPath != "" {
+		activityOpts, err := tsw.LoadActivityOptionsFromFile(rootOpts.ActivityOptionsPath)
+		if err != nil {
+			return nil, fmt.Errorf("unable to load activity options: %w", err)
+		}
+		opts = append(opts, tsw.WithActivityOptions(activityOpts))
+	}
+	if rootOpts.HTTPTaskQueuesPath != "" {
+		queues, err := tsw.LoadHTTPTaskQueuesFromFile(rootOpts.HTTPTaskQueuesPath)
+		if err != nil {
+			return nil, fmt.Errorf("unable to load http task queues: %w", err)
+		}
+		opts = append(opts, tsw.WithHTTPTaskQueues(queues))
+	}
+	if rootOpts.OutputViewsPath != "" {
+		views, err := tsw.LoadOutputViewsFromFile(rootOpts.OutputViewsPath)
+		if err != nil {
+			return nil, fmt.Errorf("unable to load output views: %w", err)
+		}
+		opts = append(opts, tsw.WithOutputViews(views))
+	}
+	if rootOpts.WorkflowRetryPath != "" {
+		retry, err := tsw.LoadRetryConfigFromFile(rootOpts.WorkflowRetryPath)
+		if err != nil {
+			return nil, fmt.Errorf("unable to load workflow retry options: %w", err)
+		}
+		opts = append(opts, tsw.WithWorkflowRetry(retry))
+	}
+
+	return opts, nil
+}
+
+// Logs the tasks of each workflow loaded, so it's clear which file has
+// been deployed
+func logWorkflowPlan(workflows []*tsw.TemporalWorkflow) {
+	taskCount := 0
+	for _, wf := range workflows {
+		tasks := make([]string, 0, len(wf.Tasks))
+		for _, t := range wf.Tasks {
+			tasks = append(tasks, fmt.Sprintf("%s (%s)", t.Key, t.Type))
+		}
+		taskCount += len(wf.Tasks)
+
+		log.Info().Str("name", wf.Name).Strs("tasks", tasks).Msg("Workflow loaded")
+	}
+
+	log.Info().Str("file", rootOpts.FilePath).Int("workflows", len(workflows)).Int("tasks", taskCount).Msg("Workflow plan")
+}
+
+// Loads the workflow file and registers the workflows and activities with a
+// new worker. The worker isn't started. The hash of each workflow's definition
+// is returned, keyed by the workflow name.
+func newWorker(c client.Client) (worker.Worker, map[string]string, error) {
+	opts, err := workflowOptions()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Load the workflow file
+	wf, err := tsw.LoadFromFile(rootOpts.FilePath, rootOpts.EnvPrefix, opts...)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error loading workflow: %w", err)
+	}
+
+	if rootOpts.Validate {
+		log.Debug().Msg("Running validation")
+		if err := wf.Validate(); err != nil {
+			return nil, nil, fmt.Errorf("failed validation: %w", err)
+		}
+	}
+
+	w := worker.New(c, rootOpts.TaskQueue, worker.Options{
+		Identity:                     rootOpts.WorkerIdentity,
+		Interceptors:                 workerInterceptors,
+		StickyScheduleToStartTimeout: rootOpts.StickyTimeout,
+	})
+
+	workflows, err := wf.BuildWorkflows()
+	if err != nil {
+		return nil, nil, fmt.Errorf("error building workflows: %w", err)
+	}
+	logWorkflowPlan(workflows)
+
+	definitions := make(map[string]string, len(workflows))
+	for _, wf := range workflows {
+		log.Debug().Str("name", wf.Name).Msg("Registering workflow")
+		w.RegisterWorkflowWithOptions(wf.Workflow, workflow.RegisterOptions{
+			Name: wf.Name,
+		})
+		definitions[wf.Name] = wf.Definition
+	}
+
+	log.Debug().Msg("Registering activities")
+	activities := wf.Activities()
+	w.RegisterActivity(activities)
+
+	// Each http call is registered with its task key so it can be identified in the UI
+	for _, name := range wf.CallHTTPActivityNames() {
+		log.Debug().Str("name", name).Msg("Registering http call activity")
+		w.RegisterActivityWithOptions(activities.CallHTTP, activity.RegisterOptions{
+			Name: name,
+		})
+	}
+
+	return w, definitions, nil
+}
+
+// How long to wait for Temporal to count a changed workflow's open runs
+const openRunsTimeout = 10 * time.Second
+
+// Gets the workflows that are registered in both and whose definition has
+// changed
+func changedWorkflows(registered, reloaded map[string]string) []string {
+	changed := make([]string, 0)
+	for _, name := range slices.Sorted(maps.Keys(registered)) {
+		if def, ok := reloaded[name]; ok && def != registered[name] {
+			changed = append(changed, name)
+		}
+	}
+	return changed
+}
+
+// Checks none of the changed workflows have open runs on the task queue, as
+// they'd be replayed against the new definition. This needs a visibility
+// store that supports counting workflows.
+func checkReloadable(c client.Client, changed []string) error {
+	for _, name := range changed {
+		ctx, cancel := context.WithTimeout(context.Background(), openRunsTimeout)
+		resp, err := c.CountWorkflow(ctx, &workflowservice.CountWorkflowExecutionsRequest{
+			Query: fmt.Sprintf(
+				"WorkflowType = %q AND TaskQueue = %q AND ExecutionStatus = 'Running'",
+				name, rootOpts.TaskQueue,
+			),
+		})
+		cancel()
+		if err != nil {
+			return fmt.Errorf("unable to count open runs of changed workflow %s: %w", name, err)
+		}
+		if count := resp.GetCount(); count > 0 {
+			return fmt.Errorf("workflow %s has changed and has %d open runs", name, count)
+		}
+	}
+
+	return nil
+}
+
+// Runs the worker, replacing it with a new worker each time a reload is
+// triggered, either by SIGHUP or by a change to the workflow file in watch mode.
+//
+// Temporal registrations can't be changed on a running worker, so a new
+// worker is built from the reloaded workflow file and swapped in. If the
+// reloaded file is invalid, the existing worker is kept. In-flight workflows
+// are picked up by the new worker and replayed against the new definitions,
+// so a reload that changes a workflow with open runs is refused, keeping the
+// existing worker.
+func runReloadableWorker(c client.Client, w worker.Worker, definitions map[string]string) error {
+	reload := make(chan struct{}, 1)
+	trigger := func() {
+		// Non-blocking - a pending reload will pick up the latest file
+		select {
+		case reload <- struct{}{}:
+		default:
+		}
+	}
+
+	if rootOpts.Reload {
+		sighup := make(chan os.Signal, 1)
+		signal.Notify(sighup, syscall.SIGHUP)
+		defer signal.Stop(sighup)
+
+		go func() {
+			for range sighup {
+				log.Info().Msg("Received SIGHUP")
+				trigger()
+			}
+		}()
+	}
+
+	if rootOpts.Watch {
+		stop, err := watchWorkflowFile(rootOpts.FilePath, watchDebounce, trigger)
+		if err != nil {
+			return fmt.Errorf("error watching workflow file: %w", err)
+		}
+		defer stop()
+	}
+
+	return runWithReload(c, w, definitions, reload)
+}
+
+// Runs the worker until interrupted, rebuilding it whenever the reload
+// channel receives
+func runWithReload(c client.Client, w worker.Worker, definitions map[string]string, reload <-chan struct{}) error {
+	if err := w.Start(); err != nil {
+		return err
+	}
+
+	interrupt := worker.InterruptCh()
+	for {
+		select {
+		case <-interrupt:
+			log.Info().Msg("Stopping worker")
+			w.Stop()
+			return nil
+		case <-reload:
+			log.Info().Str("file", rootOpts.FilePath).Msg("Reloading workflow file")
+
+			newW, newDefinitions, err := newWorker(c)
+			if err != nil {
+				log.Error().Err(err).Msg("Error reloading workflow - keeping existing workflows")
+				continue
+			}
+
+			if err := checkReloadable(c, changedWorkflows(definitions, newDefinitions)); err != nil {
+				log.Error().Err(err).Msg("Refusing to reload workflow - keeping existing workflows")
+				continue
+			}
+
+			w.Stop()
+			if err := newW.Start(); err != nil {
+				return fmt.Errorf("error starting reloaded worker: %w", err)
+			}
+			w = newW
+			definitions = newDefinitions
+
+			log.Info().Msg("Workflow file reloaded")
+		}
+	}
+}
+
+var workerOpts struct {
+	Role string
+}
+
+// workerCmd represents the worker command
+var workerCmd = &cobra.Command{
+	Use:   "worker",
+	Short: "Runs a worker, optionally for a role",
+	Long: `Runs a worker, as the root command does. The same image can be deployed as
+several workloads, each with its own role, workflow file and task queue. The
+task queue defaults to the role's name.`,
+	PreRun: func(cmd *cobra.Command, args []string) {
+		if workerOpts.Role != "" {
+			// An explicitly set task queue wins over the role
+			_, envSet := os.LookupEnv("TASK_QUEUE")
+			if !envSet && !cmd.Flags().Changed("task-queue") && !cmd.Flags().Changed("queue") {
+				rootOpts.TaskQueue = workerOpts.Role
+			}
+			log.Info().Str("role", workerOpts.Role).Str("taskQueue", rootOpts.TaskQueue).Msg("Worker role")
+		}
+		validateWorkerOpts()
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		runWorker()
+	},
+}
+
+func init() {
+	// The worker has the same options as the root command
+	workerCmd.Flags().AddFlagSet(rootCmd.Flags())
+
+	workerCmd.Flags().StringVar(
+		&workerOpts.Role,
+		"role",
+		viper.GetString("role"),
+		"Name of the worker's role - the task queue defaults to this",
+	)
+
+	workerCmd.Flags().StringVar(
+		&rootOpts.TaskQueue,
+		"queue",
+		viper.GetString("task_queue"),
+		"Task queue name - alias of --task-queue",
+	)
+
+	rootCmd.AddCommand(workerCmd)
+}
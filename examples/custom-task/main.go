@@ -0,0 +1,120 @@
+/*
+ * Copyright 2025 Simon Emms <simon@simonemms.com>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"runtime"
+
+	"github.com/mrsimonemms/golang-helpers/temporal"
+	tsw "github.com/mrsimonemms/temporal-serverless-workflow/pkg/workflow"
+	"github.com/rs/zerolog/log"
+	"github.com/serverlessworkflow/sdk-go/v3/model"
+	"go.temporal.io/sdk/client"
+	"go.temporal.io/sdk/worker"
+	"go.temporal.io/sdk/workflow"
+)
+
+const taskQueue = "custom-task"
+
+// Builds the "call: greet" task, which sets a greeting variable
+func greetTask(task *model.TaskItem, _ *tsw.Workflow) (tsw.TemporalWorkflowFunc, error) {
+	with := task.AsCallFunctionTask().With
+
+	return func(ctx workflow.Context, data *tsw.Variables, output map[string]tsw.OutputType) error {
+		name, err := tsw.ParseVariables(fmt.Sprint(with["name"]), data)
+		if err != nil {
+			return err
+		}
+
+		workflow.GetLogger(ctx).Info("Greeting", "name", name)
+		data.Data["greeting"] = fmt.Sprintf("Hello, %s", name)
+
+		return nil
+	}, nil
+}
+
+func main() {
+	// Custom handlers must be registered before the workflow is loaded
+	tsw.RegisterTaskHandler("call.greet", greetTask)
+
+	// The client and worker are heavyweight objects that should be created once per process.
+	c, err := client.Dial(client.Options{
+		Logger: temporal.NewZerologHandler(&log.Logger),
+	})
+	if err != nil {
+		log.Fatal().Err(err).Msg("Unable to create client")
+	}
+	defer c.Close()
+
+	// Load the workflow file next to this file, wherever this is run from
+	_, file, _, _ := runtime.Caller(0)
+	wf, err := tsw.LoadFromFile(filepath.Join(filepath.Dir(file), "workflow.yaml"), "TSW")
+	if err != nil {
+		//nolint:gocritic
+		log.Fatal().Err(err).Msg("Error loading workflow")
+	}
+
+	if err := wf.Validate(); err != nil {
+		log.Fatal().Err(err).Msg("Invalid workflow")
+	}
+
+	workflows, err := wf.BuildWorkflows()
+	if err != nil {
+		log.Fatal().Err(err).Msg("Error building workflows")
+	}
+
+	w := worker.New(c, taskQueue, worker.Options{})
+	for _, t := range workflows {
+		w.RegisterWorkflowWithOptions(t.Workflow, workflow.RegisterOptions{
+			Name: t.Name,
+		})
+	}
+	w.RegisterActivity(wf.Activities())
+
+	if err := w.Start(); err != nil {
+		log.Fatal().Err(err).Msg("Error starting worker")
+	}
+	defer w.Stop()
+
+	workflowOptions := client.StartWorkflowOptions{
+		TaskQueue: taskQueue,
+	}
+
+	ctx := context.Background()
+	we, err := c.ExecuteWorkflow(ctx, workflowOptions, wf.WorkflowName(), tsw.HTTPData{
+		"name": "Jane Doe",
+	})
+	if err != nil {
+		log.Fatal().Err(err).Msg("Error executing workflow")
+	}
+
+	log.Info().Str("workflowId", we.GetID()).Str("runId", we.GetRunID()).Msg("Started workflow")
+
+	var result map[string]tsw.OutputType
+	if err := we.Get(ctx, &result); err != nil {
+		log.Fatal().Err(err).Msg("Error getting response")
+	}
+
+	log.Info().Interface("result", result).Msg("Workflow completed")
+
+	fmt.Println("===")
+	fmt.Printf("%+v\n", result)
+	fmt.Println("===")
+}
@@ -0,0 +1,54 @@
+/*
+ * Copyright 2025 Simon Emms <simon@simonemms.com>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"github.com/mrsimonemms/temporal-serverless-workflow/cmd"
+	"github.com/rs/zerolog/log"
+	"go.temporal.io/sdk/interceptor"
+	"go.temporal.io/sdk/workflow"
+)
+
+// A no-op worker interceptor. Embedding the base means only the methods that
+// are needed have to be implemented.
+type workerInterceptor struct {
+	interceptor.WorkerInterceptorBase
+}
+
+func (w *workerInterceptor) InterceptWorkflow(ctx workflow.Context, next interceptor.WorkflowInboundInterceptor) interceptor.WorkflowInboundInterceptor {
+	i := &workflowInterceptor{}
+	i.Next = next
+	return i
+}
+
+type workflowInterceptor struct {
+	interceptor.WorkflowInboundInterceptorBase
+}
+
+func (w *workflowInterceptor) ExecuteWorkflow(ctx workflow.Context, in *interceptor.ExecuteWorkflowInput) (any, error) {
+	workflow.GetLogger(ctx).Info("Intercepted workflow", "type", workflow.GetInfo(ctx).WorkflowType.Name)
+
+	return w.Next.ExecuteWorkflow(ctx, in)
+}
+
+func main() {
+	// Interceptors must be registered before the command is run
+	log.Info().Msg("Registering no-op worker interceptor")
+	cmd.RegisterWorkerInterceptors(&workerInterceptor{})
+
+	cmd.Execute()
+}
@@ -23,6 +23,26 @@ import (
 	"go.temporal.io/sdk/workflow"
 )
 
+// AttemptKey is a shorter alias of _ta_attempt, the activity's attempt number
+// starting from 1. Unlike the prefixed variables, any variable with the same
+// name takes precedence.
+const AttemptKey = "attempt"
+
+// Adds the activity's variables to a copy of the variables. The jq functions
+// aren't sent to the activity, so are set from the worker's.
+func (a *activities) withActivityVars(ctx context.Context, vars *Variables) *Variables {
+	vars = vars.Clone()
+	vars.jqDefs = a.jqDefs
+
+	activityVars := GetActivityVars(ctx)
+	if _, ok := vars.Data[AttemptKey]; !ok {
+		activityVars[AttemptKey] = activityVars["_ta_attempt"]
+	}
+	vars.AddData(activityVars)
+
+	return vars
+}
+
 func GetActivityVars(ctx context.Context) HTTPData {
 	info := activity.GetInfo(ctx)
 
@@ -0,0 +1,130 @@
+/*
+ * Copyright 2025 Simon Emms <simon@simonemms.com>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package workflow
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/serverlessworkflow/sdk-go/v3/model"
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/workflow"
+	"gopkg.in/yaml.v3"
+)
+
+// ActivityOptionsConfig maps the task type (eg, CallHTTP) to the default
+// activity options for that task type
+type ActivityOptionsConfig map[string]ActivityConfig
+
+type ActivityConfig struct {
+	HeartbeatTimeout       time.Duration `yaml:"heartbeatTimeout"`
+	ScheduleToCloseTimeout time.Duration `yaml:"scheduleToCloseTimeout"`
+	ScheduleToStartTimeout time.Duration `yaml:"scheduleToStartTimeout"`
+	StartToCloseTimeout    time.Duration `yaml:"startToCloseTimeout"`
+	PriorityKey            int           `yaml:"priorityKey"`
+	Retry                  *RetryConfig  `yaml:"retry"`
+}
+
+// WithActivityOptions sets the default activity options for each task type
+func WithActivityOptions(cfg ActivityOptionsConfig) Option {
+	return func(w *Workflow) {
+		w.activityOptions = cfg
+	}
+}
+
+func LoadActivityOptionsFromFile(file string) (ActivityOptionsConfig, error) {
+	data, err := os.ReadFile(filepath.Clean(file))
+	if err != nil {
+		return nil, fmt.Errorf("error loading activity options file: %w", err)
+	}
+
+	var cfg ActivityOptionsConfig
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("error converting activity options yaml: %w", err)
+	}
+
+	return cfg, nil
+}
+
+// Build the activity options for a task. The task type defaults are applied
+// over the workflow's defaults, then the timeout of any listen foreach the task
+// is in, and any timeout or priority key set in the task wins. Any labels are
+// set as the activity's summary. If nothing is set, nil is returned and the
+// workflow's options are used.
+func (w *Workflow) taskActivityOptions(
+	taskType string,
+	task *model.TaskBase,
+	labels map[string]string,
+	defaultTimeout time.Duration,
+	defaultPriority temporal.Priority,
+) (*workflow.ActivityOptions, error) {
+	cfg, hasConfig := w.activityOptions[taskType]
+	hasTimeout := task != nil && task.Timeout != nil && task.Timeout.Timeout != nil && task.Timeout.Timeout.After != nil
+
+	var taskPriorityKey int
+	if task != nil {
+		key, err := priorityKeyFromMetadata(task.Metadata)
+		if err != nil {
+			return nil, err
+		}
+		taskPriorityKey = key
+	}
+
+	if !hasConfig && !hasTimeout && w.foreachTimeout == 0 && taskPriorityKey == 0 && len(labels) == 0 {
+		return nil, nil
+	}
+
+	opts := &workflow.ActivityOptions{
+		StartToCloseTimeout: defaultTimeout,
+		Priority:            defaultPriority,
+	}
+
+	if hasConfig {
+		if cfg.StartToCloseTimeout > 0 {
+			opts.StartToCloseTimeout = cfg.StartToCloseTimeout
+		}
+		opts.HeartbeatTimeout = cfg.HeartbeatTimeout
+		opts.ScheduleToCloseTimeout = cfg.ScheduleToCloseTimeout
+		opts.ScheduleToStartTimeout = cfg.ScheduleToStartTimeout
+		if cfg.PriorityKey > 0 {
+			opts.Priority.PriorityKey = cfg.PriorityKey
+		}
+
+		opts.RetryPolicy = cfg.Retry.RetryPolicy()
+	}
+
+	if w.foreachTimeout > 0 {
+		opts.StartToCloseTimeout = w.foreachTimeout
+	}
+	if hasTimeout {
+		timeout, err := ToDuration(task.Timeout.Timeout.After)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing task timeout: %w", err)
+		}
+		opts.StartToCloseTimeout = timeout
+	}
+	if taskPriorityKey > 0 {
+		opts.Priority.PriorityKey = taskPriorityKey
+	}
+	if len(labels) > 0 {
+		opts.Summary = labelSummary(labels)
+	}
+
+	return opts, nil
+}
@@ -0,0 +1,388 @@
+/*
+ * Copyright 2025 Simon Emms <simon@simonemms.com>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package workflow
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// The variable holding the verified token's claims, as "claims"
+const AuthKey = "$auth"
+
+// Allowed clock skew when checking a token's times
+const tokenLeeway = time.Minute
+
+// AuthConfig configures how a JWT is verified at start. One of JWKSURL or
+// KeyFile must be set.
+type AuthConfig struct {
+	// If set, the token's aud claim must contain this
+	Audience string
+
+	// Claims to expose to the workflow - all claims if empty
+	Claims []string
+
+	// If set, the token's iss claim must match this
+	Issuer string
+
+	// URL of a JSON Web Key Set
+	JWKSURL string
+
+	// File containing a PEM public key or certificate, or an HMAC secret
+	KeyFile string
+}
+
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+type jwk struct {
+	Crv string `json:"crv"`
+	E   string `json:"e"`
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	N   string `json:"n"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// AuthInput verifies the token and returns the selected claims in the input
+// format for the workflow
+func AuthInput(ctx context.Context, token string, cfg AuthConfig) (HTTPData, error) {
+	claims, err := VerifyToken(ctx, token, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(cfg.Claims) > 0 {
+		selected := map[string]any{}
+		for _, c := range cfg.Claims {
+			if v, ok := claims[c]; ok {
+				selected[c] = v
+			}
+		}
+		claims = selected
+	}
+
+	return HTTPData{
+		AuthKey: map[string]any{
+			"claims": claims,
+		},
+	}, nil
+}
+
+// VerifyToken checks the JWT's signature, times, issuer and audience and
+// returns its claims
+func VerifyToken(ctx context.Context, token string, cfg AuthConfig) (map[string]any, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("%w: token must have three parts", ErrInvalidToken)
+	}
+
+	var header jwtHeader
+	if err := decodeTokenPart(parts[0], &header); err != nil {
+		return nil, err
+	}
+
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid signature encoding", ErrInvalidToken)
+	}
+
+	keys, err := loadVerificationKeys(ctx, cfg, header.Kid)
+	if err != nil {
+		return nil, err
+	}
+
+	signed := []byte(parts[0] + "." + parts[1])
+	if !slices.ContainsFunc(keys, func(key any) bool {
+		return verifySignature(header.Alg, key, signed, sig)
+	}) {
+		return nil, fmt.Errorf("%w: signature cannot be verified", ErrInvalidToken)
+	}
+
+	var claims map[string]any
+	if err := decodeTokenPart(parts[1], &claims); err != nil {
+		return nil, err
+	}
+
+	if err := validateClaims(claims, cfg, time.Now()); err != nil {
+		return nil, err
+	}
+
+	return claims, nil
+}
+
+func decodeTokenPart(part string, target any) error {
+	b, err := base64.RawURLEncoding.DecodeString(part)
+	if err != nil {
+		return fmt.Errorf("%w: invalid encoding", ErrInvalidToken)
+	}
+	if err := json.Unmarshal(b, target); err != nil {
+		return fmt.Errorf("%w: invalid json", ErrInvalidToken)
+	}
+	return nil
+}
+
+func validateClaims(claims map[string]any, cfg AuthConfig, now time.Time) error {
+	if exp, ok := claims["exp"].(float64); ok && now.After(time.Unix(int64(exp), 0).Add(tokenLeeway)) {
+		return ErrTokenExpired
+	}
+	if nbf, ok := claims["nbf"].(float64); ok && now.Add(tokenLeeway).Before(time.Unix(int64(nbf), 0)) {
+		return fmt.Errorf("%w: token is not valid yet", ErrInvalidToken)
+	}
+
+	if cfg.Issuer != "" && claims["iss"] != cfg.Issuer {
+		return fmt.Errorf("%w: unexpected issuer", ErrInvalidToken)
+	}
+
+	if cfg.Audience != "" {
+		var aud []any
+		switch v := claims["aud"].(type) {
+		case string:
+			aud = []any{v}
+		case []any:
+			aud = v
+		}
+		if !slices.Contains(aud, any(cfg.Audience)) {
+			return fmt.Errorf("%w: unexpected audience", ErrInvalidToken)
+		}
+	}
+
+	return nil
+}
+
+// Returns the keys that may have signed the token. A JWKS is filtered by the
+// key ID if the token has one.
+func loadVerificationKeys(ctx context.Context, cfg AuthConfig, kid string) ([]any, error) {
+	if cfg.KeyFile != "" {
+		key, err := loadKeyFile(cfg.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		return []any{key}, nil
+	}
+
+	if cfg.JWKSURL != "" {
+		return fetchJWKS(ctx, cfg.JWKSURL, kid)
+	}
+
+	return nil, fmt.Errorf("%w: no verification keys configured", ErrInvalidToken)
+}
+
+func loadKeyFile(file string) (any, error) {
+	data, err := os.ReadFile(filepath.Clean(file))
+	if err != nil {
+		return nil, fmt.Errorf("error reading key file: %w", err)
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		// Not a PEM, so treat as an HMAC secret
+		secret := []byte(strings.TrimSpace(string(data)))
+		if len(secret) == 0 {
+			return nil, fmt.Errorf("key file is empty: %s", file)
+		}
+		return secret, nil
+	}
+
+	if block.Type == "CERTIFICATE" {
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing certificate: %w", err)
+		}
+		return cert.PublicKey, nil
+	}
+
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing public key: %w", err)
+	}
+	return key, nil
+}
+
+func fetchJWKS(ctx context.Context, url, kid string) ([]any, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error creating jwks request: %w", err)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching jwks: %w", err)
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("error fetching jwks: %s", resp.Status)
+	}
+
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return nil, fmt.Errorf("error decoding jwks: %w", err)
+	}
+
+	keys := make([]any, 0)
+	for _, k := range set.Keys {
+		if kid != "" && k.Kid != kid {
+			continue
+		}
+		key, err := k.publicKey()
+		if err != nil {
+			// Ignore keys of an unsupported type
+			continue
+		}
+		keys = append(keys, key)
+	}
+
+	return keys, nil
+}
+
+func (k jwk) publicKey() (any, error) {
+	decode := func(s string) ([]byte, error) {
+		return base64.RawURLEncoding.DecodeString(s)
+	}
+
+	switch k.Kty {
+	case "RSA":
+		n, err := decode(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decode(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}, nil
+	case "EC":
+		curves := map[string]elliptic.Curve{
+			"P-256": elliptic.P256(),
+			"P-384": elliptic.P384(),
+			"P-521": elliptic.P521(),
+		}
+		curve, ok := curves[k.Crv]
+		if !ok {
+			return nil, fmt.Errorf("unsupported curve: %s", k.Crv)
+		}
+		x, err := decode(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decode(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{
+			Curve: curve,
+			X:     new(big.Int).SetBytes(x),
+			Y:     new(big.Int).SetBytes(y),
+		}, nil
+	case "OKP":
+		x, err := decode(k.X)
+		if err != nil {
+			return nil, err
+		}
+		if k.Crv != "Ed25519" || len(x) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("unsupported curve: %s", k.Crv)
+		}
+		return ed25519.PublicKey(x), nil
+	default:
+		return nil, fmt.Errorf("unsupported key type: %s", k.Kty)
+	}
+}
+
+// Checks the signature with the key. The key's type must match the algorithm
+// so a public key can't be used as an HMAC secret.
+func verifySignature(alg string, key any, signed, sig []byte) bool {
+	hashes := map[string]crypto.Hash{
+		"256": crypto.SHA256,
+		"384": crypto.SHA384,
+		"512": crypto.SHA512,
+	}
+
+	if alg == "EdDSA" {
+		k, ok := key.(ed25519.PublicKey)
+		return ok && ed25519.Verify(k, signed, sig)
+	}
+
+	if len(alg) != 5 {
+		return false
+	}
+	hash, ok := hashes[alg[2:]]
+	if !ok {
+		return false
+	}
+	h := hash.New()
+	h.Write(signed)
+	digest := h.Sum(nil)
+
+	switch alg[:2] {
+	case "HS":
+		k, ok := key.([]byte)
+		if !ok {
+			return false
+		}
+		mac := hmac.New(hash.New, k)
+		mac.Write(signed)
+		return hmac.Equal(mac.Sum(nil), sig)
+	case "RS":
+		k, ok := key.(*rsa.PublicKey)
+		return ok && rsa.VerifyPKCS1v15(k, hash, digest, sig) == nil
+	case "PS":
+		k, ok := key.(*rsa.PublicKey)
+		return ok && rsa.VerifyPSS(k, hash, digest, sig, nil) == nil
+	case "ES":
+		k, ok := key.(*ecdsa.PublicKey)
+		if !ok || len(sig)%2 != 0 {
+			return false
+		}
+		size := len(sig) / 2
+		r := new(big.Int).SetBytes(sig[:size])
+		s := new(big.Int).SetBytes(sig[size:])
+		return ecdsa.Verify(k, digest, r, s)
+	default:
+		return false
+	}
+}
@@ -0,0 +1,74 @@
+/*
+ * Copyright 2025 Simon Emms <simon@simonemms.com>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package workflow
+
+import (
+	"fmt"
+
+	"go.temporal.io/api/enums/v1"
+)
+
+// BusinessKeyPolicy is what happens when a workflow is started for a business
+// key that already has a running workflow
+type BusinessKeyPolicy string
+
+const (
+	// Fail to start the new workflow
+	BusinessKeyPolicyReject BusinessKeyPolicy = "reject"
+	// Return the running workflow rather than starting a new one
+	BusinessKeyPolicyUseExisting BusinessKeyPolicy = "use-existing"
+	// Terminate the running workflow and start the new one
+	BusinessKeyPolicyTerminateExisting BusinessKeyPolicy = "terminate-existing"
+)
+
+// ConflictPolicy is the Temporal workflow ID conflict policy for the business
+// key policy
+func (p BusinessKeyPolicy) ConflictPolicy() (enums.WorkflowIdConflictPolicy, error) {
+	switch p {
+	case BusinessKeyPolicyReject:
+		return enums.WORKFLOW_ID_CONFLICT_POLICY_FAIL, nil
+	case BusinessKeyPolicyUseExisting:
+		return enums.WORKFLOW_ID_CONFLICT_POLICY_USE_EXISTING, nil
+	case BusinessKeyPolicyTerminateExisting:
+		return enums.WORKFLOW_ID_CONFLICT_POLICY_TERMINATE_EXISTING, nil
+	default:
+		return enums.WORKFLOW_ID_CONFLICT_POLICY_UNSPECIFIED, fmt.Errorf("%w: %s", ErrInvalidBusinessKeyPolicy, p)
+	}
+}
+
+// BusinessKeyWorkflowID builds the workflow ID from a jq expression run
+// against the input, eg ${ .orderId }. Temporal only allows one running
+// workflow per ID, so workflows for the same key can't run at the same time.
+func BusinessKeyWorkflowID(name, expression string, input HTTPData) (string, error) {
+	value, err := EvaluateJQ(expression, "businessKey", &Variables{Data: input})
+	if err != nil {
+		return "", err
+	}
+
+	switch v := value.(type) {
+	case nil:
+		return "", fmt.Errorf("%w: %s returned no value", ErrInvalidBusinessKey, expression)
+	case string:
+		if v == "" {
+			return "", fmt.Errorf("%w: %s returned an empty string", ErrInvalidBusinessKey, expression)
+		}
+	case map[string]any, []any:
+		return "", fmt.Errorf("%w: %s must return a string or number", ErrInvalidBusinessKey, expression)
+	}
+
+	return fmt.Sprintf("%s-%v", name, value), nil
+}
@@ -24,13 +24,26 @@ type (
 )
 
 const (
-	CallHTTPErr    ErrType = "CallHTTP error"
-	IfStatementErr ErrType = "IfStatement error"
+	CallHTTPErr       ErrType = "CallHTTP error"
+	DoTimeoutErr      ErrType = "DoTimeout error"
+	ExpressionErr     ErrType = "Expression error"
+	GuardErr          ErrType = "Guard error"
+	IfStatementErr    ErrType = "IfStatement error"
+	InputErr          ErrType = "Input error"
+	OutputViewErr     ErrType = "OutputView error"
+	ResponseSchemaErr ErrType = "ResponseSchema error"
+	TaskLimitErr      ErrType = "TaskLimit error"
+	TimeoutErr        ErrType = "Timeout error"
 )
 
 const (
-	CallHTTPResultType ResultType = "CallHTTP"
-	ForkResultType     ResultType = "Fork"
+	CallHTTPResultType    ResultType = "CallHTTP"
+	CompleteResultType    ResultType = "Complete"
+	EmitResultType        ResultType = "Emit"
+	ErrorResultType       ResultType = "Error"
+	ForkResultType        ResultType = "Fork"
+	ForkTimeoutResultType ResultType = "ForkTimeout"
+	TimelineResultType    ResultType = "Timeline"
 )
 
 const defaultWorkflowTimeout = time.Minute * 5
@@ -0,0 +1,89 @@
+/*
+ * Copyright 2025 Simon Emms <simon@simonemms.com>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package workflow
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/serverlessworkflow/sdk-go/v3/model"
+)
+
+// TaskHandlerBuilder builds the function run for a custom task
+type TaskHandlerBuilder func(task *model.TaskItem, w *Workflow) (TemporalWorkflowFunc, error)
+
+var (
+	customTaskHandlers   = map[string]TaskHandlerBuilder{}
+	customTaskHandlersMu sync.RWMutex
+)
+
+// RegisterTaskHandler lets applications embedding the package run task types
+// that aren't implemented here. The task type is either the name in the
+// supported schema, eg "run" or "switch", or "call.<function>" for a call to a
+// custom function, eg "call.acme". The tasks implemented here always take
+// precedence, so custom handlers only fill the gaps.
+//
+// Register the handlers before the workflow is loaded.
+func RegisterTaskHandler(taskType string, builder TaskHandlerBuilder) {
+	customTaskHandlersMu.Lock()
+	defer customTaskHandlersMu.Unlock()
+
+	customTaskHandlers[taskType] = builder
+}
+
+// Gets the custom handler registered for the task, if any
+func customTaskHandler(task *model.TaskItem) (string, TaskHandlerBuilder) {
+	taskType := customTaskType(task)
+	if taskType == "" {
+		return "", nil
+	}
+
+	customTaskHandlersMu.RLock()
+	defer customTaskHandlersMu.RUnlock()
+
+	return taskType, customTaskHandlers[taskType]
+}
+
+// The type a custom handler is registered against for the task
+func customTaskType(task *model.TaskItem) string {
+	if fn := task.AsCallFunctionTask(); fn != nil {
+		return fmt.Sprintf("call.%s", fn.Call)
+	}
+
+	for _, t := range taskSupportList {
+		if t.match(task) {
+			return t.name
+		}
+	}
+
+	return ""
+}
+
+// Builds a task with its custom handler
+func customTaskImpl(task *model.TaskItem, w *Workflow) (TemporalWorkflowFunc, string, error) {
+	taskType, builder := customTaskHandler(task)
+	if builder == nil {
+		return nil, "", nil
+	}
+
+	fn, err := builder(task, w)
+	if err != nil {
+		return nil, "", fmt.Errorf("error building custom %s task %s: %w", taskType, task.Key, err)
+	}
+
+	return fn, taskType, nil
+}
@@ -0,0 +1,89 @@
+/*
+ * Copyright 2025 Simon Emms <simon@simonemms.com>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package workflow
+
+import (
+	"fmt"
+	"time"
+
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/workflow"
+)
+
+// How long before the run times out that the tasks are stopped, leaving time
+// for the workflow to return the output so far
+const runDeadlineMargin = 5 * time.Second
+
+// Versions the deadline timer so workflows started before it was added replay
+// without it
+const runDeadlineChange = "run-deadline"
+
+// Gets how long the tasks can run for before the run times out, less the
+// margin. This is zero if there's no run timeout or it's too short to stop
+// early.
+func runDeadline(ctx workflow.Context) time.Duration {
+	info := workflow.GetInfo(ctx)
+	if info.WorkflowRunTimeout == 0 {
+		return 0
+	}
+
+	deadline := info.WorkflowStartTime.Add(info.WorkflowRunTimeout - runDeadlineMargin)
+	return max(deadline.Sub(workflow.Now(ctx)), 0)
+}
+
+// Runs the tasks, stopping them just before the run times out. Temporal would
+// otherwise discard the output, so ErrRunDeadline is returned and the output
+// so far can be returned with the error.
+func withRunDeadline(run TemporalWorkflowFunc) TemporalWorkflowFunc {
+	return func(ctx workflow.Context, vars *Variables, output map[string]OutputType) error {
+		remaining := runDeadline(ctx)
+		if remaining == 0 || workflow.GetVersion(ctx, runDeadlineChange, workflow.DefaultVersion, 1) == workflow.DefaultVersion {
+			return run(ctx, vars, output)
+		}
+
+		tasksCtx, cancel := workflow.WithCancel(ctx)
+		defer cancel()
+
+		future, settable := workflow.NewFuture(ctx)
+		workflow.Go(tasksCtx, func(ctx workflow.Context) {
+			settable.SetError(run(ctx, vars, output))
+		})
+
+		var err error
+		timedOut := false
+		workflow.NewSelector(ctx).
+			AddFuture(future, func(f workflow.Future) {
+				err = f.Get(ctx, nil)
+			}).
+			AddFuture(workflow.NewTimer(tasksCtx, remaining), func(f workflow.Future) {
+				timedOut = true
+			}).
+			Select(ctx)
+
+		if timedOut {
+			return fmt.Errorf("%w: stopped %s before the run timeout", ErrRunDeadline, runDeadlineMargin)
+		}
+
+		return err
+	}
+}
+
+// The error returned when the run is about to time out. The output so far is
+// set as the error's details, so callers can get it with Details.
+func runDeadlineError(err error, output map[string]OutputType) error {
+	return temporal.NewNonRetryableApplicationError(err.Error(), string(TimeoutErr), err, output)
+}
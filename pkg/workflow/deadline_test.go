@@ -0,0 +1,94 @@
+/*
+ * Copyright 2025 Simon Emms <simon@simonemms.com>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package workflow
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"go.temporal.io/sdk/client"
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/workflow"
+)
+
+func TestRunDeadline(t *testing.T) {
+	tests := []struct {
+		name     string
+		version  workflow.Version
+		stopped  bool
+		expected []string
+	}{
+		{
+			name:     "stopped before the run timeout",
+			version:  1,
+			stopped:  true,
+			expected: []string{"first"},
+		},
+		{
+			// The test environment doesn't enforce the run timeout, so the
+			// tasks run to the end
+			name:     "started before the deadline was added",
+			version:  workflow.DefaultVersion,
+			expected: []string{"first", "second"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			recorded := recordTasks(t)
+
+			w := loadTestWorkflow(t, `
+document:
+  dsl: 1.0.0
+  namespace: test
+  name: deadline
+  version: 0.0.1
+do:
+  - first:
+      call: record
+  - pause:
+      wait:
+        minutes: 1
+  - second:
+      call: record
+`)
+
+			env := newTestEnvironment(w)
+			env.SetStartWorkflowOptions(client.StartWorkflowOptions{
+				WorkflowRunTimeout: 30 * time.Second,
+			})
+			env.OnGetVersion(runDeadlineChange, workflow.DefaultVersion, 1).Return(test.version)
+
+			_, err := runTestWorkflowInEnvironment(t, env, buildTestWorkflow(t, w, "deadline"), HTTPData{})
+
+			var appErr *temporal.ApplicationError
+			stopped := errors.As(err, &appErr) && appErr.Type() == string(TimeoutErr)
+			if stopped != test.stopped {
+				t.Errorf("expected stopped to be %t, got %v", test.stopped, err)
+			}
+			if !test.stopped && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+
+			if !slices.Equal(*recorded, test.expected) {
+				t.Errorf("expected %v, got %v", test.expected, *recorded)
+			}
+		})
+	}
+}
@@ -0,0 +1,94 @@
+/*
+ * Copyright 2025 Simon Emms <simon@simonemms.com>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package workflow
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Definitions are hashes of the raw definition of each registered workflow, so
+// a reload can tell which workflows have changed. Formatting and comments
+// don't change the hash.
+type definitions struct {
+	root string
+	// Keyed by the top-level do task registered as its own workflow
+	do map[string]string
+}
+
+func hashDefinition(def any) (string, error) {
+	b, err := yaml.Marshal(def)
+	if err != nil {
+		return "", fmt.Errorf("error hashing workflow definition: %w", err)
+	}
+
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// Hashes the whole document for the root workflow. A top-level do task is
+// hashed with the rest of the document, except the other tasks, as it uses the
+// document's settings, such as the timeout and functions.
+func findDefinitions(doc any) (*definitions, error) {
+	root, err := hashDefinition(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	defs := &definitions{
+		root: root,
+		do:   make(map[string]string),
+	}
+
+	d, ok := doc.(map[string]any)
+	if !ok {
+		return defs, nil
+	}
+	tasks, ok := d["do"].([]any)
+	if !ok {
+		return defs, nil
+	}
+
+	settings := make(map[string]any, len(d))
+	for k, v := range d {
+		if k != "do" {
+			settings[k] = v
+		}
+	}
+
+	for _, item := range tasks {
+		task, ok := item.(map[string]any)
+		if !ok || len(task) != 1 {
+			continue
+		}
+		for key, def := range task {
+			if t, ok := def.(map[string]any); !ok || t["do"] == nil {
+				continue
+			}
+			hash, err := hashDefinition(map[string]any{"settings": settings, "task": def})
+			if err != nil {
+				return nil, err
+			}
+			defs.do[key] = hash
+		}
+	}
+
+	return defs, nil
+}
@@ -0,0 +1,83 @@
+/*
+ * Copyright 2025 Simon Emms <simon@simonemms.com>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package workflow
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDefinitionHash(t *testing.T) {
+	const doc = `
+document:
+  dsl: 1.0.0
+  namespace: test
+  name: multiple
+  version: 0.0.1
+do:
+  - workflow1:
+      do:
+        - wait:
+            wait:
+              seconds: 5
+  - workflow2:
+      do:
+        - wait:
+            wait:
+              seconds: 10
+`
+
+	hashes := func(doc string) map[string]string {
+		t.Helper()
+
+		workflows, err := loadTestWorkflow(t, doc).BuildWorkflows()
+		if err != nil {
+			t.Fatalf("unexpected error building workflows: %v", err)
+		}
+
+		defs := make(map[string]string, len(workflows))
+		for _, wf := range workflows {
+			if wf.Definition == "" {
+				t.Fatalf("expected a definition hash for %s", wf.Name)
+			}
+			defs[wf.Name] = wf.Definition
+		}
+		return defs
+	}
+
+	before := hashes(doc)
+
+	// Comments and formatting don't change the definition
+	formatted := hashes(doc + "# a comment\n")
+	for name, def := range before {
+		if formatted[name] != def {
+			t.Errorf("expected %s to be unchanged by a comment", name)
+		}
+	}
+
+	// Changing one workflow changes it and the root, but not its sibling
+	changed := hashes(strings.Replace(doc, "seconds: 10", "seconds: 20", 1))
+	if changed["workflow1"] != before["workflow1"] {
+		t.Error("expected workflow1 to be unchanged")
+	}
+	if changed["workflow2"] == before["workflow2"] {
+		t.Error("expected workflow2 to have changed")
+	}
+	if changed["multiple"] == before["multiple"] {
+		t.Error("expected the root workflow to have changed")
+	}
+}
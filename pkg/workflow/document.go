@@ -0,0 +1,95 @@
+/*
+ * Copyright 2025 Simon Emms <simon@simonemms.com>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package workflow
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/workflow"
+)
+
+// DocumentKey is the variable with the workflow document's name, version, tags
+// and metadata, eg {{ index . "$document" "version" }} or ${ .["$document"].tags }
+const DocumentKey = "$document"
+
+// Versions setting the document variable, so workflows started before it was
+// added replay without its side effects or search attribute
+const documentVariablesChange = "document-variables"
+
+// Returns true if the tag value needs interpolating
+func isExpression(s string) bool {
+	return isJQExpression(s) || strings.Contains(s, "{{")
+}
+
+// Sets the document variable. Any tags that are expressions are evaluated
+// against the workflow's variables in a side effect, as with a set task. If
+// the search attribute is configured, the tags are upserted to it as
+// "key:value" so workflows can be filtered by their tags.
+func (t *TemporalWorkflow) setDocumentVariables(ctx workflow.Context, vars *Variables) error {
+	if t.Document == nil {
+		return nil
+	}
+	if workflow.GetVersion(ctx, documentVariablesChange, workflow.DefaultVersion, 1) == workflow.DefaultVersion {
+		return nil
+	}
+
+	tags := make(map[string]any, len(t.Document.Tags))
+	keys := slices.Sorted(maps.Keys(t.Document.Tags))
+	for _, key := range keys {
+		value := t.Document.Tags[key]
+		if !isExpression(value) {
+			tags[key] = value
+			continue
+		}
+
+		v, err := setTaskValue(ctx, "document.tags."+key, value, vars)
+		if err != nil {
+			return err
+		}
+		tags[key] = v
+	}
+
+	vars.Data[DocumentKey] = HTTPData{
+		"name":      t.Document.Name,
+		"namespace": t.Document.Namespace,
+		"version":   t.Document.Version,
+		"title":     t.Document.Title,
+		"summary":   t.Document.Summary,
+		"tags":      tags,
+		"metadata":  t.Document.Metadata,
+	}
+
+	if t.TagsSearchAttribute == "" || len(tags) == 0 {
+		return nil
+	}
+
+	values := make([]string, 0, len(tags))
+	for _, key := range keys {
+		values = append(values, fmt.Sprintf("%s:%v", key, tags[key]))
+	}
+
+	attr := temporal.NewSearchAttributeKeyKeywordList(t.TagsSearchAttribute)
+	if err := workflow.UpsertTypedSearchAttributes(ctx, attr.ValueSet(values)); err != nil {
+		return fmt.Errorf("error upserting tags search attribute: %w", err)
+	}
+
+	return nil
+}
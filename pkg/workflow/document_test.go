@@ -0,0 +1,100 @@
+/*
+ * Copyright 2025 Simon Emms <simon@simonemms.com>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package workflow
+
+import (
+	"reflect"
+	"slices"
+	"testing"
+
+	"github.com/serverlessworkflow/sdk-go/v3/model"
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/workflow"
+)
+
+func TestDocumentVariables(t *testing.T) {
+	tests := []struct {
+		name     string
+		version  workflow.Version
+		expected any
+		tags     []string
+	}{
+		{
+			name:    "set with the tags",
+			version: 1,
+			expected: HTTPData{
+				"name":      "document",
+				"namespace": "test",
+				"version":   "0.0.1",
+				"title":     "",
+				"summary":   "",
+				"tags":      map[string]any{"team": "payments", "region": "eu"},
+				"metadata":  map[string]any(nil),
+			},
+			tags: []string{"region:eu", "team:payments"},
+		},
+		{
+			name:    "started before the document variable was added",
+			version: workflow.DefaultVersion,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			vars := captureVars(t)
+
+			var tags []string
+			RegisterTaskHandler("call.tags", func(task *model.TaskItem, w *Workflow) (TemporalWorkflowFunc, error) {
+				return func(ctx workflow.Context, data *Variables, output map[string]OutputType) error {
+					attr := temporal.NewSearchAttributeKeyKeywordList("TswTags")
+					tags, _ = workflow.GetTypedSearchAttributes(ctx).GetKeywordList(attr)
+					return nil
+				}, nil
+			})
+
+			w := loadTestWorkflow(t, `
+document:
+  dsl: 1.0.0
+  namespace: test
+  name: document
+  version: 0.0.1
+  tags:
+    team: payments
+    region: "{{ .region }}"
+do:
+  - capture:
+      call: capture
+  - tags:
+      call: tags
+`, WithTagsSearchAttribute("TswTags"))
+
+			env := newTestEnvironment(w)
+			env.OnGetVersion(documentVariablesChange, workflow.DefaultVersion, 1).Return(test.version)
+
+			if _, err := runTestWorkflowInEnvironment(t, env, buildTestWorkflow(t, w, "document"), HTTPData{"region": "eu"}); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if !reflect.DeepEqual((*vars)[DocumentKey], test.expected) {
+				t.Errorf("expected %#v, got %#v", test.expected, (*vars)[DocumentKey])
+			}
+			if !slices.Equal(tags, test.tags) {
+				t.Errorf("expected tags %v, got %v", test.tags, tags)
+			}
+		})
+	}
+}
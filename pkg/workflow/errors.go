@@ -16,15 +16,198 @@
 
 package workflow
 
-import "fmt"
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
 
 var (
-	ErrDuplicateKey          = fmt.Errorf("duplicate key found")
-	ErrInvalidType           = fmt.Errorf("invalid type given")
-	ErrNotString             = fmt.Errorf("input must be a string")
-	ErrUnsetListenIDTask     = fmt.Errorf("listen task id is not set")
-	ErrUnsetListenTypeTask   = fmt.Errorf("listen task type is not set")
-	ErrUnknownListenTypeTask = fmt.Errorf("listen task type is not known")
-	ErrUnsupportedTask       = fmt.Errorf("task not supported")
-	ErrUnsupportedDSL        = fmt.Errorf("unsupported dsl")
+	ErrCallHTTPBodyAndBodyFile    = fmt.Errorf("call http cannot set both body and bodyFile")
+	ErrDuplicateKey               = fmt.Errorf("duplicate key found")
+	ErrGuardFailed                = fmt.Errorf("guard no longer holds")
+	ErrIncludeCycle               = fmt.Errorf("include cycle detected")
+	ErrInputTooLarge              = fmt.Errorf("workflow input is too large - pass a reference to the data instead")
+	ErrInvalidBusinessKey         = fmt.Errorf("invalid business key")
+	ErrInvalidBusinessKeyPolicy   = fmt.Errorf("business key policy must be reject, use-existing or terminate-existing")
+	ErrInvalidContinueOnError     = fmt.Errorf("continue on error must be a boolean")
+	ErrInvalidDuration            = fmt.Errorf("invalid duration")
+	ErrInvalidEmptyResponse       = fmt.Errorf("empty response must be null or object")
+	ErrInvalidForkOutput          = fmt.Errorf("fork output must be map or array")
+	ErrInvalidGuard               = fmt.Errorf("guard must be a non-empty jq expression")
+	ErrInvalidHeaders             = fmt.Errorf("headers must be a map or an expression giving an object of strings")
+	ErrInvalidHTTPErrorBodySize   = fmt.Errorf("http error body size must be a positive integer")
+	ErrInvalidHTTPMethod          = fmt.Errorf("http method must be a token")
+	ErrInvalidHTTPTaskQueue       = fmt.Errorf("http task queue must map a host to a task queue")
+	ErrInvalidInclude             = fmt.Errorf("$include must be a file path")
+	ErrInvalidLabels              = fmt.Errorf("labels must be a map of strings")
+	ErrInvalidListenAmount        = fmt.Errorf("invalid listen amount")
+	ErrInvalidMaxTasks            = fmt.Errorf("max tasks must be a positive integer")
+	ErrInvalidOutputKey           = fmt.Errorf("output key must be a non-empty string")
+	ErrInvalidOutputView          = fmt.Errorf("invalid output view")
+	ErrInvalidPaginate            = fmt.Errorf("invalid pagination")
+	ErrInvalidPriorityKey         = fmt.Errorf("priority key must be a positive integer")
+	ErrInvalidResponseSchema      = fmt.Errorf("invalid response schema")
+	ErrInvalidSigningPolicy       = fmt.Errorf("signing policy must set one of hmac or sigv4")
+	ErrInvalidStatusRange         = fmt.Errorf("invalid http status range")
+	ErrInvalidToken               = fmt.Errorf("invalid token")
+	ErrInvalidType                = fmt.Errorf("invalid type given")
+	ErrMissingCloudEventAttribute = fmt.Errorf("missing required cloudevent attribute")
+	ErrMultipleListenStrategies   = fmt.Errorf("only one of listen all, any, one or until can be set")
+	ErrNegativeDuration           = fmt.Errorf("duration cannot be negative")
+	ErrNoAWSCredentials           = fmt.Errorf("no aws credentials found")
+	ErrNotString                  = fmt.Errorf("input must be a string")
+	ErrQueryProjectionAndData     = fmt.Errorf("query cannot set both projection and data")
+	ErrReservedInputKey           = fmt.Errorf("input cannot set a reserved key")
+	ErrResponseSchemaMismatch     = fmt.Errorf("response does not match schema")
+	ErrRunDeadline                = fmt.Errorf("workflow run is about to time out")
+	ErrTaskLimitExceeded          = fmt.Errorf("workflow exceeded the maximum number of tasks")
+	ErrTokenExpired               = fmt.Errorf("token has expired")
+	ErrUnknownOutputView          = fmt.Errorf("unknown output view")
+	ErrUnknownSigningPolicy       = fmt.Errorf("unknown signing policy")
+	ErrUnknownTaskKey             = fmt.Errorf("unknown task key")
+	ErrUnknownTaskName            = fmt.Errorf("unknown task name")
+	ErrUnsetListenForeachDo       = fmt.Errorf("listen task foreach do is not set")
+	ErrUnsetListenIDTask          = fmt.Errorf("listen task id is not set")
+	ErrUnsetListenTypeTask        = fmt.Errorf("listen task type is not set")
+	ErrUnknownListenTypeTask      = fmt.Errorf("listen task type is not known")
+	ErrUnsupportedContentEncoding = fmt.Errorf("unsupported content encoding")
+	ErrUnsupportedContentType     = fmt.Errorf("content type not supported")
+	ErrUnsupportedTask            = fmt.Errorf("task not supported")
+	ErrUnsupportedDSL             = fmt.Errorf("unsupported dsl")
+	ErrUnsupportedTryCatch        = fmt.Errorf("try catch option not supported")
 )
+
+// Maximum length of an expression to show in an error message
+const expressionSnippetLength = 60
+
+// ExpressionError gives context to a failed template or jq expression so
+// that it can be found in the workflow definition
+type ExpressionError struct {
+	Task       string
+	Field      string
+	Expression string
+	Line       int
+	Column     int
+	Err        error
+}
+
+func (e *ExpressionError) Error() string {
+	var b strings.Builder
+	b.WriteString("expression error")
+	if e.Task != "" {
+		fmt.Fprintf(&b, " in task %q", e.Task)
+	}
+	if e.Field != "" {
+		fmt.Fprintf(&b, " field %q", e.Field)
+	}
+	if e.Line > 0 {
+		fmt.Fprintf(&b, " at line %d", e.Line)
+		if e.Column > 0 {
+			fmt.Fprintf(&b, ", column %d", e.Column)
+		}
+	}
+
+	snippet := strings.TrimSpace(e.Expression)
+	if len(snippet) > expressionSnippetLength {
+		snippet = snippet[:expressionSnippetLength] + "..."
+	}
+	fmt.Fprintf(&b, " (%q): %s", snippet, e.Err)
+
+	return b.String()
+}
+
+func (e *ExpressionError) Unwrap() error {
+	return e.Err
+}
+
+// WithExpressionContext adds the task key and field name to an
+// ExpressionError. Existing values are kept so the innermost context wins.
+// Any other error is returned untouched.
+func WithExpressionContext(err error, task, field string) error {
+	var exprErr *ExpressionError
+	if errors.As(err, &exprErr) {
+		if exprErr.Task == "" {
+			exprErr.Task = task
+		}
+		if exprErr.Field == "" {
+			exprErr.Field = field
+		}
+	}
+	return err
+}
+
+// UnsupportedTaskError is returned when a workflow uses a task that's not yet
+// implemented. The code is stable so it can be matched by tooling and the hint
+// suggests what to do instead.
+type UnsupportedTaskError struct {
+	Code string
+	Hint string
+	Key  string
+	Task string
+}
+
+// The unsupported tasks. These can be matched with errors.Is.
+var (
+	ErrUnsupportedCallGRPCTask = &UnsupportedTaskError{
+		Code: "UNSUPPORTED_CALL_GRPC",
+		Hint: "gRPC calls are not yet implemented; use an HTTP call to a gRPC gateway instead",
+		Task: "call.grpc",
+	}
+	ErrUnsupportedCallOpenAPITask = &UnsupportedTaskError{
+		Code: "UNSUPPORTED_CALL_OPENAPI",
+		Hint: "OpenAPI calls are not yet implemented; use an HTTP call to the operation's endpoint instead",
+		Task: "call.openapi",
+	}
+	ErrUnsupportedForTask = &UnsupportedTaskError{
+		Code: "UNSUPPORTED_FOR",
+		Hint: "for is not yet implemented; use a fork with a branch for each item if they're known in advance",
+		Task: "for",
+	}
+	ErrUnsupportedRaiseTask = &UnsupportedTaskError{
+		Code: "UNSUPPORTED_RAISE",
+		Hint: "raise is not yet implemented; an HTTP call returning an error status will fail the workflow",
+		Task: "raise",
+	}
+	ErrUnsupportedRunTask = &UnsupportedTaskError{
+		Code: "UNSUPPORTED_RUN",
+		Hint: "run is not yet implemented; put the container, script or workflow behind an HTTP endpoint and use an HTTP call",
+		Task: "run",
+	}
+	ErrUnsupportedSwitchTask = &UnsupportedTaskError{
+		Code: "UNSUPPORTED_SWITCH",
+		Hint: "switch is not yet implemented; use an if on each task instead",
+		Task: "switch",
+	}
+)
+
+func (e *UnsupportedTaskError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s: %s", ErrUnsupportedTask, e.Task)
+	if e.Key != "" {
+		fmt.Fprintf(&b, " in task %q", e.Key)
+	}
+	fmt.Fprintf(&b, " [%s]", e.Code)
+	if e.Hint != "" {
+		fmt.Fprintf(&b, " - %s", e.Hint)
+	}
+
+	return b.String()
+}
+
+// Matches any UnsupportedTaskError with the same code, regardless of the key
+func (e *UnsupportedTaskError) Is(target error) bool {
+	t, ok := target.(*UnsupportedTaskError)
+	return ok && t.Code == e.Code
+}
+
+func (e *UnsupportedTaskError) Unwrap() error {
+	return ErrUnsupportedTask
+}
+
+// Returns a copy of the error for the given task key
+func (e *UnsupportedTaskError) withKey(key string) *UnsupportedTaskError {
+	err := *e
+	err.Key = key
+	return &err
+}
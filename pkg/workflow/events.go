@@ -0,0 +1,97 @@
+/*
+ * Copyright 2025 Simon Emms <simon@simonemms.com>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package workflow
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/serverlessworkflow/sdk-go/v3/model"
+	"go.temporal.io/sdk/workflow"
+)
+
+// Number of events that can be emitted locally before something listens
+const localEventBufferSize = 100
+
+// Events emitted within a workflow are delivered to any listener in the same
+// workflow without going through the Temporal server. Channels are keyed by
+// the event type, which is the listener's ID.
+type localEvents map[string]workflow.Channel
+
+func (v *Variables) localEventChannel(ctx workflow.Context, eventType string) workflow.Channel {
+	if v.events == nil {
+		v.events = make(localEvents)
+	}
+
+	ch, ok := v.events[eventType]
+	if !ok {
+		ch = workflow.NewBufferedChannel(ctx, localEventBufferSize)
+		v.events[eventType] = ch
+	}
+
+	return ch
+}
+
+// Deliver an emitted event to listeners in this workflow
+func publishLocalEvent(ctx workflow.Context, data *Variables, event *CloudEvent) {
+	logger := workflow.GetLogger(ctx)
+
+	if !data.localEventChannel(ctx, event.Type).SendAsync(event) {
+		logger.Warn("Local event buffer full - event not delivered locally", "type", event.Type, "id", event.ID)
+	}
+}
+
+// Checks the event matches the listener's correlation rules. Each "from" is a
+// jq expression run against the event and is compared to the interpolated
+// "expect" value. With no "expect", the "from" value must just exist.
+func eventCorrelates(event *CloudEvent, filter *model.EventFilter, data *Variables) (bool, error) {
+	if len(filter.Correlate) == 0 {
+		return true, nil
+	}
+
+	b, err := json.Marshal(event)
+	if err != nil {
+		return false, fmt.Errorf("error converting event to json: %w", err)
+	}
+	var e HTTPData
+	if err := decodeJSON(b, &e); err != nil {
+		return false, fmt.Errorf("error converting event from json: %w", err)
+	}
+	eventVars := &Variables{Data: e, jqDefs: data.jqDefs}
+
+	for key, c := range filter.Correlate {
+		value, err := EvaluateJQ(c.From, "correlate."+key+".from", eventVars)
+		if err != nil {
+			return false, err
+		}
+		if value == nil {
+			return false, nil
+		}
+
+		if c.Expect != "" {
+			expect, err := ParseVariables(c.Expect, data)
+			if err != nil {
+				return false, WithExpressionContext(err, "", "correlate."+key+".expect")
+			}
+			if fmt.Sprint(value) != expect {
+				return false, nil
+			}
+		}
+	}
+
+	return true, nil
+}
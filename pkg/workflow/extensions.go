@@ -0,0 +1,138 @@
+/*
+ * Copyright 2025 Simon Emms <simon@simonemms.com>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package workflow
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+
+	"github.com/serverlessworkflow/sdk-go/v3/model"
+	"gopkg.in/yaml.v3"
+)
+
+// Some tasks support fields that aren't in the SDK's model, so these are read
+// from the raw workflow definition. Decoding resolves any anchors, aliases and
+// merge keys, and any files included are merged in.
+func loadRawDefinition(data []byte, file string) (any, error) {
+	var doc any
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("error loading yaml: %w", err)
+	}
+
+	abs, err := filepath.Abs(file)
+	if err != nil {
+		return nil, fmt.Errorf("error resolving workflow file path: %w", err)
+	}
+
+	return resolveIncludes(doc, filepath.Dir(abs), []string{abs})
+}
+
+// Walks the raw workflow definition, calling fn with the key and definition of
+// every task found
+func walkTaskDefinitions(node any, fn func(key string, def any) error) error {
+	switch v := node.(type) {
+	case []any:
+		for _, item := range v {
+			// Tasks are a list of single-key objects
+			if task, ok := item.(map[string]any); ok && len(task) == 1 {
+				for key, def := range task {
+					if err := fn(key, def); err != nil {
+						return err
+					}
+				}
+			}
+
+			if err := walkTaskDefinitions(item, fn); err != nil {
+				return err
+			}
+		}
+	case map[string]any:
+		for _, item := range v {
+			if err := walkTaskDefinitions(item, fn); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// OnCancel is a list of tasks that's run if the workflow is cancelled, such as
+// to release a lock. This can be set on the document, for the tasks at the
+// top-level, or on a top-level do task that's registered as a workflow.
+type OnCancel struct {
+	OnCancel *model.TaskList `json:"onCancel,omitempty"`
+}
+
+func getOnCancel(def any) (*model.TaskList, error) {
+	d, ok := def.(map[string]any)
+	if !ok || d["onCancel"] == nil {
+		return nil, nil
+	}
+
+	// Convert via JSON so the task list is parsed by the SDK
+	b, err := json.Marshal(map[string]any{"onCancel": d["onCancel"]})
+	if err != nil {
+		return nil, fmt.Errorf("error converting onCancel to json: %w", err)
+	}
+	var ext OnCancel
+	if err := json.Unmarshal(b, &ext); err != nil {
+		return nil, fmt.Errorf("error parsing onCancel: %w", err)
+	}
+
+	return ext.OnCancel, nil
+}
+
+// Find the onCancel task lists in the raw workflow definition. The document's
+// is returned separately to those of the top-level do tasks, which are keyed
+// by the task key.
+func findOnCancel(doc any) (*model.TaskList, map[string]*model.TaskList, error) {
+	root, err := getOnCancel(doc)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	found := make(map[string]*model.TaskList)
+
+	d, ok := doc.(map[string]any)
+	if !ok {
+		return root, found, nil
+	}
+	tasks, ok := d["do"].([]any)
+	if !ok {
+		return root, found, nil
+	}
+
+	for _, item := range tasks {
+		task, ok := item.(map[string]any)
+		if !ok {
+			continue
+		}
+		for key, def := range task {
+			list, err := getOnCancel(def)
+			if err != nil {
+				return nil, nil, fmt.Errorf("%w: %s", err, key)
+			}
+			if list != nil {
+				found[key] = list
+			}
+		}
+	}
+
+	return root, found, nil
+}
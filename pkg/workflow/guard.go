@@ -0,0 +1,94 @@
+/*
+ * Copyright 2025 Simon Emms <simon@simonemms.com>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package workflow
+
+import (
+	"fmt"
+
+	"github.com/itchyny/gojq"
+	"github.com/serverlessworkflow/sdk-go/v3/model"
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/workflow"
+)
+
+// The metadata key for an expression that must hold while a listen or wait
+// task is waiting
+const guardMetadata = "guard"
+
+// Gets the task's guard expression, checking it's valid jq. This is empty if
+// the task has no guard.
+func taskGuard(key string, metadata map[string]any, jqDefs []*gojq.FuncDef) (string, error) {
+	v, ok := metadata[guardMetadata]
+	if !ok || v == nil {
+		return "", nil
+	}
+
+	expression, ok := v.(string)
+	if !ok || expression == "" {
+		return "", fmt.Errorf("%w: %s", ErrInvalidGuard, key)
+	}
+
+	if _, err := parseJQ(model.SanitizeExpr(expression), "metadata.guard", jqDefs...); err != nil {
+		return "", WithExpressionContext(err, key, "metadata.guard")
+	}
+
+	return expression, nil
+}
+
+// Returns a check that errors once the guard no longer holds, so the wait
+// can be abandoned. Without a guard, this always holds.
+func guardCheck(expression, key string, data *Variables) func() error {
+	return func() error {
+		if expression == "" {
+			return nil
+		}
+
+		ok, err := evaluateCondition(expression, "metadata.guard", data)
+		if err != nil {
+			return WithExpressionContext(err, key, "metadata.guard")
+		}
+		if !ok {
+			err := fmt.Errorf("%w: %s", ErrGuardFailed, key)
+			return temporal.NewNonRetryableApplicationError(err.Error(), string(GuardErr), err)
+		}
+
+		return nil
+	}
+}
+
+// Returns a future that's ready once the guard no longer holds, so it can be
+// added to a selector. This is nil if there's no guard.
+func watchGuard(ctx workflow.Context, expression string, check func() error) workflow.Future {
+	if expression == "" {
+		return nil
+	}
+
+	future, settable := workflow.NewFuture(ctx)
+	workflow.Go(ctx, func(ctx workflow.Context) {
+		var guardErr error
+		if err := workflow.Await(ctx, func() bool {
+			guardErr = check()
+			return guardErr != nil
+		}); err != nil {
+			// The wait has finished and the context is cancelled
+			return
+		}
+		settable.SetError(guardErr)
+	})
+
+	return future
+}
@@ -0,0 +1,133 @@
+/*
+ * Copyright 2025 Simon Emms <simon@simonemms.com>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package workflow
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/workflow"
+)
+
+// Query name to get the workflow's debug state. Temporal reserves query
+// names starting with "__" so this can't be "__state".
+const StateQueryName = "_tsw_state"
+
+// HTTPDebugRecord is a redacted copy of an HTTP request and its response
+type HTTPDebugRecord struct {
+	Method          string            `json:"method"`
+	URL             string            `json:"url"`
+	RequestHeaders  map[string]string `json:"requestHeaders,omitempty"`
+	RequestBody     string            `json:"requestBody,omitempty"`
+	StatusCode      int               `json:"statusCode,omitempty"`
+	ResponseHeaders map[string]string `json:"responseHeaders,omitempty"`
+	ResponseBody    string            `json:"responseBody,omitempty"`
+	Error           string            `json:"error,omitempty"`
+}
+
+func newHTTPDebugRecord(req *http.Request, body []byte) *HTTPDebugRecord {
+	return &HTTPDebugRecord{
+		Method:         req.Method,
+		URL:            req.URL.String(),
+		RequestHeaders: redactHeaders(req.Header),
+		RequestBody:    string(body),
+	}
+}
+
+func (r *HTTPDebugRecord) setResponse(resp *http.Response, body []byte) {
+	r.StatusCode = resp.StatusCode
+	r.ResponseHeaders = redactHeaders(resp.Header)
+	r.ResponseBody = string(body)
+}
+
+// Serialise writes to the debug file across concurrent activities
+var httpDebugFileLock sync.Mutex
+
+// Appends the record to the debug file as a line of JSON
+func writeHTTPDebugFile(file string, record *HTTPDebugRecord) error {
+	b, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("error converting http debug record to json: %w", err)
+	}
+
+	httpDebugFileLock.Lock()
+	defer httpDebugFileLock.Unlock()
+
+	f, err := os.OpenFile(filepath.Clean(file), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("error opening http debug file: %w", err)
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+
+	if _, err := f.Write(append(b, '\n')); err != nil {
+		return fmt.Errorf("error writing http debug file: %w", err)
+	}
+
+	return nil
+}
+
+// Records the HTTP call in the workflow's ring buffer, dropping the oldest
+// records once full
+func (v *Variables) recordHTTPDebug(record *HTTPDebugRecord, size int) {
+	if record == nil || size <= 0 {
+		return
+	}
+
+	v.httpDebug = append(v.httpDebug, record)
+	if len(v.httpDebug) > size {
+		v.httpDebug = v.httpDebug[len(v.httpDebug)-size:]
+	}
+}
+
+// Gets the debug record from a failed CallHTTP activity, if there is one
+func httpDebugFromError(err error) *HTTPDebugRecord {
+	var appErr *temporal.ApplicationError
+	if !errors.As(err, &appErr) || !appErr.HasDetails() {
+		return nil
+	}
+
+	var details struct {
+		Debug *HTTPDebugRecord `json:"debug"`
+	}
+	if err := appErr.Details(&details); err != nil {
+		return nil
+	}
+
+	return details.Debug
+}
+
+// Registers the state query, which returns the identity of the worker that
+// answered it and any recorded HTTP calls
+func registerStateQuery(ctx workflow.Context, vars *Variables, workerIdentity string) error {
+	return workflow.SetQueryHandler(ctx, StateQueryName, func() (map[string]any, error) {
+		state := map[string]any{
+			"workerIdentity": workerIdentity,
+		}
+		if vars.httpDebug != nil {
+			state["httpDebug"] = vars.httpDebug
+		}
+		return state, nil
+	})
+}
@@ -0,0 +1,89 @@
+/*
+ * Copyright 2025 Simon Emms <simon@simonemms.com>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package workflow
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+)
+
+// Decodes the response body by its Content-Encoding. Go's transport only does
+// this for gzip when it set the Accept-Encoding header itself, so a request
+// with a custom Accept-Encoding header would otherwise get the encoded bytes.
+func decodedBody(resp *http.Response) (io.Reader, error) {
+	var body io.Reader = resp.Body
+	if resp.Uncompressed {
+		// Already decoded by the transport
+		return body, nil
+	}
+
+	// Encodings are listed in the order they were applied
+	encodings := strings.Split(resp.Header.Get("Content-Encoding"), ",")
+	for i := len(encodings) - 1; i >= 0; i-- {
+		encoding := strings.ToLower(strings.TrimSpace(encodings[i]))
+		if encoding == "" || encoding == "identity" {
+			continue
+		}
+
+		// An empty body, such as a 204, has nothing to decode
+		buf := bufio.NewReader(body)
+		if _, err := buf.Peek(1); errors.Is(err, io.EOF) {
+			return buf, nil
+		}
+
+		switch encoding {
+		case "br":
+			body = brotli.NewReader(buf)
+		case "gzip", "x-gzip":
+			r, err := gzip.NewReader(buf)
+			if err != nil {
+				return nil, fmt.Errorf("error decoding gzip body: %w", err)
+			}
+			body = r
+		case "deflate":
+			// This should be zlib wrapped, but some servers send raw deflate
+			header, err := buf.Peek(2)
+			if err == nil && isZlibHeader(header) {
+				r, err := zlib.NewReader(buf)
+				if err != nil {
+					return nil, fmt.Errorf("error decoding deflate body: %w", err)
+				}
+				body = r
+			} else {
+				body = flate.NewReader(buf)
+			}
+		default:
+			return nil, fmt.Errorf("%w: %s", ErrUnsupportedContentEncoding, encoding)
+		}
+	}
+
+	return body, nil
+}
+
+// Checks for the zlib header - deflate compression, with the check bits set
+func isZlibHeader(b []byte) bool {
+	return b[0]&0x0f == 8 && (uint16(b[0])<<8|uint16(b[1]))%31 == 0
+}
@@ -0,0 +1,70 @@
+/*
+ * Copyright 2025 Simon Emms <simon@simonemms.com>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package workflow
+
+import (
+	"encoding/base64"
+	"strings"
+)
+
+// DefaultHTTPErrorBodySize is the default number of bytes of an HTTP call's
+// response body attached to its error. Error pages can be large and the error
+// is stored in the workflow's history.
+const DefaultHTTPErrorBodySize = 4096
+
+// WithHTTPErrorBodySize sets the number of bytes of a failed HTTP call's
+// response body that are attached to its error. 0 is unlimited.
+func WithHTTPErrorBodySize(size int) Option {
+	return func(w *Workflow) {
+		w.httpErrorBodySize = size
+	}
+}
+
+// Builds the details of a failed HTTP call. If the body's too big, it's cut
+// down and returned as a string or base64, and truncated is set - a partial
+// body can't be decoded as JSON.
+func (a *activities) httpErrorDetails(
+	statusCode int,
+	body []byte,
+	bodyStr, bodyBase64 string,
+	bodyJSON any,
+	debug *HTTPDebugRecord,
+) HTTPData {
+	details := HTTPData{
+		"status": statusCode,
+		"body":   bodyStr,
+		"base64": bodyBase64,
+		"json":   bodyJSON,
+		"debug":  debug,
+	}
+
+	if a.httpErrorBodySize <= 0 || len(body) <= a.httpErrorBodySize {
+		return details
+	}
+
+	body = body[:a.httpErrorBodySize]
+	if bodyBase64 != "" {
+		details["base64"] = base64.StdEncoding.EncodeToString(body)
+	} else {
+		// The cut may be through a multi-byte character
+		details["body"] = strings.ToValidUTF8(string(body), "")
+		details["json"] = nil
+	}
+	details["truncated"] = true
+
+	return details
+}
@@ -0,0 +1,299 @@
+/*
+ * Copyright 2025 Simon Emms <simon@simonemms.com>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package workflow
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/itchyny/gojq"
+	"github.com/serverlessworkflow/sdk-go/v3/model"
+	"go.temporal.io/sdk/activity"
+	"go.temporal.io/sdk/temporal"
+)
+
+const (
+	defaultPaginateMaxPages = 10
+	defaultPaginateMaxBytes = 10 * 1024 * 1024
+)
+
+// CallHTTPPaginate follows the pages of a paginated response. The next page is
+// found from either a header, such as Link, or a jq expression on the body.
+// Each page's items are concatenated into a single array in bodyJSON.
+type CallHTTPPaginate struct {
+	// Header with the next page's URL. If this is Link, the rel="next" URL is
+	// used.
+	NextHeader string `json:"nextHeader,omitempty"`
+	// jq expression on the body for the next page's URL, or its token if
+	// tokenQuery is set, eg ${ .nextPageToken }
+	NextPath string `json:"nextPath,omitempty"`
+	// Query parameter the token is sent as on the next request
+	TokenQuery string `json:"tokenQuery,omitempty"`
+	// jq expression on the body for the page's items, eg ${ .items }. Defaults
+	// to the body, which must be an array.
+	Items string `json:"items,omitempty"`
+	// The most pages to get - defaults to 10
+	MaxPages int `json:"maxPages,omitempty"`
+	// The most bytes of response bodies to get - defaults to 10MiB
+	MaxBytes int `json:"maxBytes,omitempty"`
+}
+
+// Checks the pagination is valid when the workflow is loaded
+func (p *CallHTTPPaginate) validate(download bool, jqDefs []*gojq.FuncDef) error {
+	if (p.NextHeader == "") == (p.NextPath == "") {
+		return fmt.Errorf("%w: set one of nextHeader or nextPath", ErrInvalidPaginate)
+	}
+	if download {
+		return fmt.Errorf("%w: paginated calls cannot be downloaded", ErrInvalidPaginate)
+	}
+	if p.TokenQuery != "" && p.NextPath == "" {
+		return fmt.Errorf("%w: tokenQuery needs nextPath", ErrInvalidPaginate)
+	}
+	if p.MaxPages < 0 || p.MaxBytes < 0 {
+		return fmt.Errorf("%w: maxPages and maxBytes cannot be negative", ErrInvalidPaginate)
+	}
+
+	if p.NextPath != "" {
+		if _, err := parseJQ(model.SanitizeExpr(p.NextPath), "with.paginate.nextPath", jqDefs...); err != nil {
+			return err
+		}
+	}
+	if p.Items != "" {
+		if _, err := parseJQ(model.SanitizeExpr(p.Items), "with.paginate.items", jqDefs...); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (p *CallHTTPPaginate) maxPages() int {
+	if p.MaxPages == 0 {
+		return defaultPaginateMaxPages
+	}
+	return p.MaxPages
+}
+
+func (p *CallHTTPPaginate) maxBytes() int {
+	if p.MaxBytes == 0 {
+		return defaultPaginateMaxBytes
+	}
+	return p.MaxBytes
+}
+
+// Follows the pages from the first result, returning the last page's result
+// with the items of every page as its bodyJSON. The pages are got in a single
+// activity, so a failed page gets them all again on retry.
+func (a *activities) paginate(
+	ctx context.Context,
+	callHttp *model.CallHTTP,
+	ext *CallHTTPExtensions,
+	vars *Variables,
+	result *CallHTTPResult,
+) (*CallHTTPResult, error) {
+	logger := activity.GetLogger(ctx)
+	p := ext.With.Paginate
+	firstURL := result.requestURL
+
+	items := make([]any, 0)
+	pages := 0
+	size := 0
+	for {
+		pages++
+		size += result.size
+
+		pageItems, err := p.pageItems(result, a.jqDefs)
+		if err != nil {
+			logger.Error("Error getting page items", "page", pages, "error", err)
+			return nil, err
+		}
+		items = append(items, pageItems...)
+
+		next, err := p.nextURL(result, firstURL, a.jqDefs)
+		if err != nil {
+			logger.Error("Error getting next page", "page", pages, "error", err)
+			return nil, err
+		}
+		if next == "" {
+			break
+		}
+
+		if pages >= p.maxPages() || size >= p.maxBytes() {
+			logger.Warn("Stopped paginating before the last page", "pages", pages, "bytes", size)
+			result.Truncated = true
+			break
+		}
+
+		logger.Debug("Getting next page", "page", pages+1, "url", next)
+		if result, err = a.callHTTP(ctx, callHttp, ext, vars, next); err != nil {
+			return nil, err
+		}
+	}
+
+	result.Body = ""
+	result.BodyJSON = items
+	result.Pages = pages
+
+	return result, nil
+}
+
+// Gets the page's body as JSON. Only objects are decoded into bodyJSON, so an
+// array is decoded from the body.
+func pageBody(result *CallHTTPResult) (any, error) {
+	if result.BodyJSON != nil {
+		return result.BodyJSON, nil
+	}
+	if strings.TrimSpace(result.Body) == "" {
+		return nil, nil
+	}
+
+	var body any
+	if err := decodeJSON([]byte(result.Body), &body); err != nil {
+		return nil, fmt.Errorf("%w: page body must be json", ErrInvalidPaginate)
+	}
+	return body, nil
+}
+
+// Gets the items in the page
+func (p *CallHTTPPaginate) pageItems(result *CallHTTPResult, jqDefs []*gojq.FuncDef) ([]any, error) {
+	body, err := pageBody(result)
+	if err != nil {
+		return nil, temporal.NewNonRetryableApplicationError(err.Error(), string(CallHTTPErr), err)
+	}
+
+	if p.Items != "" {
+		if body, err = evaluatePageJQ(p.Items, "with.paginate.items", body, jqDefs); err != nil {
+			return nil, err
+		}
+	}
+
+	switch v := body.(type) {
+	case nil:
+		return nil, nil
+	case []any:
+		return v, nil
+	default:
+		err := fmt.Errorf("%w: page items must be an array", ErrInvalidPaginate)
+		return nil, temporal.NewNonRetryableApplicationError(err.Error(), string(CallHTTPErr), err)
+	}
+}
+
+// Gets the next page's URL. This is empty on the last page.
+func (p *CallHTTPPaginate) nextURL(result *CallHTTPResult, firstURL string, jqDefs []*gojq.FuncDef) (string, error) {
+	var next string
+	if p.NextHeader != "" {
+		next = result.header.Get(p.NextHeader)
+		if http.CanonicalHeaderKey(p.NextHeader) == "Link" {
+			next = linkNext(next)
+		}
+	} else {
+		body, err := pageBody(result)
+		if err != nil {
+			return "", temporal.NewNonRetryableApplicationError(err.Error(), string(CallHTTPErr), err)
+		}
+		v, err := evaluatePageJQ(p.NextPath, "with.paginate.nextPath", body, jqDefs)
+		if err != nil {
+			return "", err
+		}
+		if v != nil {
+			next = fmt.Sprint(v)
+		}
+	}
+
+	if next == "" {
+		return "", nil
+	}
+
+	if p.TokenQuery != "" {
+		// The token is added to the first request, keeping its query
+		u, err := url.Parse(firstURL)
+		if err != nil {
+			return "", fmt.Errorf("error parsing page url: %w", err)
+		}
+		q := u.Query()
+		q.Set(p.TokenQuery, next)
+		u.RawQuery = q.Encode()
+		return u.String(), nil
+	}
+
+	// The next URL can be relative to the current page
+	base, err := url.Parse(result.requestURL)
+	if err != nil {
+		return "", fmt.Errorf("error parsing page url: %w", err)
+	}
+	ref, err := url.Parse(next)
+	if err != nil {
+		err = fmt.Errorf("%w: invalid next page url %q", ErrInvalidPaginate, next)
+		return "", temporal.NewNonRetryableApplicationError(err.Error(), string(CallHTTPErr), err)
+	}
+
+	return base.ResolveReference(ref).String(), nil
+}
+
+// Runs a pagination jq expression against the page's body, returning the
+// first result. A broken expression won't fix itself on retry so is treated as
+// non-retryable.
+func evaluatePageJQ(expression, field string, body any, jqDefs []*gojq.FuncDef) (any, error) {
+	expression = model.SanitizeExpr(expression)
+
+	code, err := parseJQ(expression, field, jqDefs...)
+	if err != nil {
+		return nil, temporal.NewNonRetryableApplicationError(err.Error(), string(ExpressionErr), err)
+	}
+
+	v, ok := code.Run(body).Next()
+	if !ok {
+		return nil, nil
+	}
+	if err, ok := v.(error); ok {
+		err = &ExpressionError{
+			Field:      field,
+			Expression: expression,
+			Err:        err,
+		}
+		return nil, temporal.NewNonRetryableApplicationError(err.Error(), string(ExpressionErr), err)
+	}
+
+	return v, nil
+}
+
+// Gets the rel="next" URL from a Link header, eg
+// <https://example.com/items?page=2>; rel="next", <...>; rel="last"
+func linkNext(header string) string {
+	for link := range strings.SplitSeq(header, ",") {
+		target, params, ok := strings.Cut(link, ";")
+		if !ok {
+			continue
+		}
+
+		for param := range strings.SplitSeq(params, ";") {
+			name, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+			if !strings.EqualFold(name, "rel") {
+				continue
+			}
+			if slices.Contains(strings.Fields(strings.ToLower(strings.Trim(value, `"`))), "next") {
+				return strings.Trim(strings.TrimSpace(target), "<>")
+			}
+		}
+	}
+
+	return ""
+}
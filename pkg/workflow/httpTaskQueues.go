@@ -0,0 +1,87 @@
+/*
+ * Copyright 2025 Simon Emms <simon@simonemms.com>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package workflow
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// HTTPTaskQueues maps a hostname to the task queue its HTTP calls are run on,
+// such as a pool of workers allowed to reach the internet. A leading "*."
+// matches any subdomain, eg *.example.com matches api.example.com.
+type HTTPTaskQueues map[string]string
+
+// WithHTTPTaskQueues routes HTTP calls to a task queue by their hostname.
+// Unmatched hosts use the workflow's task queue.
+func WithHTTPTaskQueues(queues HTTPTaskQueues) Option {
+	return func(w *Workflow) {
+		w.httpTaskQueues = queues
+	}
+}
+
+func LoadHTTPTaskQueuesFromFile(file string) (HTTPTaskQueues, error) {
+	data, err := os.ReadFile(filepath.Clean(file))
+	if err != nil {
+		return nil, fmt.Errorf("error loading http task queues file: %w", err)
+	}
+
+	var queues HTTPTaskQueues
+	if err := yaml.Unmarshal(data, &queues); err != nil {
+		return nil, fmt.Errorf("error converting http task queues yaml: %w", err)
+	}
+
+	for host, queue := range queues {
+		if host == "" || queue == "" {
+			return nil, fmt.Errorf("%w: %q: %q", ErrInvalidHTTPTaskQueue, host, queue)
+		}
+	}
+
+	return queues, nil
+}
+
+// Gets the task queue for the endpoint's host. An exact match wins, then the
+// most specific wildcard. This is empty if nothing matches.
+func (q HTTPTaskQueues) taskQueue(endpoint string) (string, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return "", fmt.Errorf("error parsing endpoint: %w", err)
+	}
+	host := strings.ToLower(u.Hostname())
+
+	if queue, ok := q[host]; ok {
+		return queue, nil
+	}
+
+	var match, queue string
+	for pattern, v := range q {
+		suffix, ok := strings.CutPrefix(strings.ToLower(pattern), "*")
+		if !ok || !strings.HasPrefix(suffix, ".") || !strings.HasSuffix(host, suffix) {
+			continue
+		}
+		if len(suffix) > len(match) {
+			match, queue = suffix, v
+		}
+	}
+
+	return queue, nil
+}
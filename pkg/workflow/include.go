@@ -0,0 +1,113 @@
+/*
+ * Copyright 2025 Simon Emms <simon@simonemms.com>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package workflow
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// The key used to include another YAML file
+const includeKey = "$include"
+
+// Gets the path if the node is an include, eg {"$include": "./auth.yaml"}
+func includePath(node any) (string, bool, error) {
+	m, ok := node.(map[string]any)
+	if !ok || len(m) != 1 {
+		return "", false, nil
+	}
+	v, ok := m[includeKey]
+	if !ok {
+		return "", false, nil
+	}
+
+	path, ok := v.(string)
+	if !ok || path == "" {
+		return "", true, fmt.Errorf("%w: %v", ErrInvalidInclude, v)
+	}
+	return path, true, nil
+}
+
+// Replaces any includes with the contents of the file. Paths are relative to
+// the including file. If an include in a list is itself a list, such as a
+// shared sequence of tasks, its items are merged into the parent list.
+func resolveIncludes(node any, dir string, stack []string) (any, error) {
+	switch v := node.(type) {
+	case map[string]any:
+		if path, ok, err := includePath(v); err != nil {
+			return nil, err
+		} else if ok {
+			return loadInclude(path, dir, stack)
+		}
+
+		for key, item := range v {
+			resolved, err := resolveIncludes(item, dir, stack)
+			if err != nil {
+				return nil, err
+			}
+			v[key] = resolved
+		}
+	case []any:
+		list := make([]any, 0, len(v))
+		for _, item := range v {
+			_, isInclude, _ := includePath(item)
+
+			resolved, err := resolveIncludes(item, dir, stack)
+			if err != nil {
+				return nil, err
+			}
+
+			if items, ok := resolved.([]any); ok && isInclude {
+				list = append(list, items...)
+				continue
+			}
+			list = append(list, resolved)
+		}
+		return list, nil
+	}
+
+	return node, nil
+}
+
+func loadInclude(path, dir string, stack []string) (any, error) {
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(dir, path)
+	}
+	path = filepath.Clean(path)
+
+	if slices.Contains(stack, path) {
+		return nil, fmt.Errorf("%w: %s", ErrIncludeCycle, strings.Join(append(stack, path), " -> "))
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error loading included file: %w", err)
+	}
+
+	var doc any
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("error loading included yaml %s: %w", path, err)
+	}
+
+	// Copy the stack so sibling includes don't see each other
+	return resolveIncludes(doc, filepath.Dir(path), append(slices.Clone(stack), path))
+}
@@ -0,0 +1,159 @@
+/*
+ * Copyright 2025 Simon Emms <simon@simonemms.com>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package workflow
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// Temporal rejects payloads over 2MB by default. The input is checked before
+// it's sent so the error is clear.
+const DefaultMaxInputSize = 2 * 1024 * 1024
+
+const (
+	// The variable holding the base64 encoded contents of the input file
+	InputFileKey = "_tsw_input_file"
+
+	// The variable holding the path to the input when it's been offloaded
+	InputRefKey = "_tsw_input_ref"
+
+	// The variable listing the task keys to skip, eg those already done
+	SkipTasksKey = "_tsw_skip_tasks"
+
+	// The variable holding the task key to start from, skipping those before it
+	StartFromKey = "_tsw_start_from"
+
+	// The variable holding the name of the view the output is returned as
+	OutputViewKey = "_tsw_output_view"
+)
+
+// The prefixes of the variables set from the workflow and activity info
+var reservedPrefixes = []string{"_tw_", "_ta_"}
+
+func isReservedKey(key string) bool {
+	return slices.ContainsFunc(reservedPrefixes, func(prefix string) bool {
+		return strings.HasPrefix(key, prefix)
+	})
+}
+
+// CheckReservedKeys returns an error if the input sets any of the variables
+// reserved for the workflow and activity info, so they can't be spoofed, eg
+// to pass a condition on the workflow ID
+func CheckReservedKeys(input HTTPData) error {
+	for _, key := range slices.Sorted(maps.Keys(input)) {
+		if isReservedKey(key) {
+			return fmt.Errorf("%w: %s", ErrReservedInputKey, key)
+		}
+	}
+
+	return nil
+}
+
+// Removes the tasks to skip or before the task to start from, so a partially
+// successful workflow can be resumed. These are only the workflow's own
+// tasks - tasks inside a do or fork aren't skipped.
+func (t *TemporalWorkflow) withSkippedTasks(input HTTPData) (*TemporalWorkflow, error) {
+	var skip []string
+	if v, ok := input[SkipTasksKey]; ok {
+		list, ok := v.([]any)
+		if !ok {
+			return nil, fmt.Errorf("%w: %s must be a list", ErrInvalidType, SkipTasksKey)
+		}
+		for _, key := range list {
+			skip = append(skip, fmt.Sprint(key))
+		}
+	}
+
+	var startFrom string
+	if v, ok := input[StartFromKey]; ok {
+		startFrom = fmt.Sprint(v)
+	}
+
+	if len(skip) == 0 && startFrom == "" {
+		return t, nil
+	}
+
+	keys := make([]string, 0, len(t.Tasks))
+	for _, task := range t.Tasks {
+		keys = append(keys, task.Key)
+	}
+	for _, key := range skip {
+		if !slices.Contains(keys, key) {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownTaskKey, key)
+		}
+	}
+
+	start := 0
+	if startFrom != "" {
+		if start = slices.Index(keys, startFrom); start == -1 {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownTaskKey, startFrom)
+		}
+	}
+
+	wf := *t
+	wf.Tasks = make([]TemporalWorkflowTask, 0, len(t.Tasks))
+	for _, task := range t.Tasks[start:] {
+		if !slices.Contains(skip, task.Key) {
+			wf.Tasks = append(wf.Tasks, task)
+		}
+	}
+
+	return &wf, nil
+}
+
+// CheckInputSize returns an error if the workflow input is over the maximum
+// size, in bytes. The size is of the JSON, so any data conversion such as
+// encryption may add to this.
+func CheckInputSize(input HTTPData, maxSize int) error {
+	b, err := json.Marshal(input)
+	if err != nil {
+		return fmt.Errorf("error converting input to json: %w", err)
+	}
+
+	if len(b) > maxSize {
+		return fmt.Errorf("%w: %d bytes is over the limit of %d bytes", ErrInputTooLarge, len(b), maxSize)
+	}
+
+	return nil
+}
+
+// OffloadInput writes the input to a file in the directory and returns input
+// that references it in the InputRefKey variable. Only the reference is stored
+// in the workflow's history. The directory must be readable by the workers.
+func OffloadInput(input HTTPData, dir string) (HTTPData, error) {
+	b, err := json.Marshal(input)
+	if err != nil {
+		return nil, fmt.Errorf("error converting input to json: %w", err)
+	}
+
+	file := filepath.Join(dir, uuid.NewString()+".json")
+	if err := os.WriteFile(file, b, 0o600); err != nil {
+		return nil, fmt.Errorf("error offloading input: %w", err)
+	}
+
+	return HTTPData{
+		InputRefKey: file,
+	}, nil
+}
@@ -0,0 +1,75 @@
+/*
+ * Copyright 2025 Simon Emms <simon@simonemms.com>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package workflow
+
+import (
+	"errors"
+	"testing"
+
+	"go.temporal.io/sdk/temporal"
+)
+
+const reservedKeyWorkflow = `
+document:
+  dsl: 1.0.0
+  namespace: test
+  name: reserved
+  version: 0.0.1
+do:
+  - capture:
+      call: capture
+`
+
+func TestReservedInputKey(t *testing.T) {
+	vars := captureVars(t)
+	w := loadTestWorkflow(t, reservedKeyWorkflow)
+
+	_, err := runTestWorkflow(t, w, buildTestWorkflow(t, w, "reserved"), HTTPData{
+		"_tw_workflow_execution_id": "spoofed",
+	})
+
+	var appErr *temporal.ApplicationError
+	if !errors.As(err, &appErr) || appErr.Type() != string(InputErr) || !appErr.NonRetryable() {
+		t.Fatalf("expected a non-retryable %s, got %v", InputErr, err)
+	}
+	if len(*vars) > 0 {
+		t.Error("expected no tasks to be run")
+	}
+}
+
+func TestReservedEnvKey(t *testing.T) {
+	vars := captureVars(t)
+
+	// The env prefix matches the reserved variables
+	t.Setenv("_tw_workflow_execution_id", "spoofed")
+	t.Setenv("_tw_custom", "value")
+	w, err := LoadFromFile(writeTestWorkflow(t, reservedKeyWorkflow), "_tw_")
+	if err != nil {
+		t.Fatalf("error loading workflow: %v", err)
+	}
+
+	if _, err := runTestWorkflow(t, w, buildTestWorkflow(t, w, "reserved"), HTTPData{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if id := (*vars)["_tw_workflow_execution_id"]; id == "" || id == "spoofed" {
+		t.Errorf("expected the workflow's own id, got %v", id)
+	}
+	if v := (*vars)["_tw_custom"]; v != nil {
+		t.Errorf("expected the reserved envvar to be ignored, got %v", v)
+	}
+}
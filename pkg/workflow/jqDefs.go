@@ -0,0 +1,76 @@
+/*
+ * Copyright 2025 Simon Emms <simon@simonemms.com>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package workflow
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/itchyny/gojq"
+)
+
+const jqDefsField = "use.jqFunctions"
+
+// Find the jq function definitions in the raw workflow definition. These are
+// set in use.jqFunctions, eg "def double: . * 2;", and are available in every
+// jq expression in the workflow.
+func findJQDefs(doc any) ([]*gojq.FuncDef, error) {
+	d, ok := doc.(map[string]any)
+	if !ok {
+		return nil, nil
+	}
+	use, ok := d["use"].(map[string]any)
+	if !ok || use["jqFunctions"] == nil {
+		return nil, nil
+	}
+
+	defs, ok := use["jqFunctions"].(string)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrNotString, jqDefsField)
+	}
+
+	return parseJQDefs(defs)
+}
+
+// Parses the function definitions. These are compiled with an identity body
+// so any syntax error, or call to an unknown function, fails when the workflow
+// is loaded.
+func parseJQDefs(defs string) ([]*gojq.FuncDef, error) {
+	query, err := gojq.Parse(defs + " .")
+	if err != nil {
+		exprErr := &ExpressionError{
+			Field:      jqDefsField,
+			Expression: defs,
+			Err:        err,
+		}
+		var parseErr *gojq.ParseError
+		if errors.As(err, &parseErr) {
+			exprErr.Line, exprErr.Column = offsetToPosition(defs, parseErr.Offset)
+		}
+		return nil, exprErr
+	}
+
+	if _, err := gojq.Compile(query, jqFunctions...); err != nil {
+		return nil, &ExpressionError{
+			Field:      jqDefsField,
+			Expression: defs,
+			Err:        err,
+		}
+	}
+
+	return query.FuncDefs, nil
+}
@@ -0,0 +1,64 @@
+/*
+ * Copyright 2025 Simon Emms <simon@simonemms.com>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package workflow
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	commonpb "go.temporal.io/api/common/v1"
+	"go.temporal.io/sdk/converter"
+)
+
+// Decodes JSON with numbers as json.Number rather than float64, so integers
+// such as IDs keep their exact value when they're interpolated
+func decodeJSON(data []byte, v any) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	return decoder.Decode(v)
+}
+
+// The default JSON payload converter decodes numbers as float64, so 1234567
+// renders as 1.234567e+06 in a template
+type jsonNumberPayloadConverter struct {
+	*converter.JSONPayloadConverter
+}
+
+// FromPayload converts a single payload to a value, keeping numbers as
+// json.Number
+func (c *jsonNumberPayloadConverter) FromPayload(payload *commonpb.Payload, valuePtr any) error {
+	if err := decodeJSON(payload.GetData(), valuePtr); err != nil {
+		return fmt.Errorf("%w: %v", converter.ErrUnableToDecode, err)
+	}
+	return nil
+}
+
+// NewDataConverter is the default Temporal data converter, but with JSON
+// numbers decoded as json.Number rather than float64. The worker and any
+// clients starting workflows should use the same data converter.
+func NewDataConverter() converter.DataConverter {
+	return converter.NewCompositeDataConverter(
+		converter.NewNilPayloadConverter(),
+		converter.NewByteSlicePayloadConverter(),
+		converter.NewProtoJSONPayloadConverter(),
+		converter.NewProtoPayloadConverter(),
+		&jsonNumberPayloadConverter{
+			JSONPayloadConverter: converter.NewJSONPayloadConverter(),
+		},
+	)
+}
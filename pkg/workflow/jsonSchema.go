@@ -0,0 +1,378 @@
+/*
+ * Copyright 2025 Simon Emms <simon@simonemms.com>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package workflow
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"math"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// A compiled subset of JSON Schema, used to check HTTP responses. Keywords
+// that aren't supported fail when the workflow is loaded rather than being
+// ignored, so a schema never passes something it was meant to reject.
+type jsonSchema struct {
+	types                []string
+	properties           map[string]*jsonSchema
+	required             []string
+	additionalProperties *jsonSchema
+	noAdditional         bool
+	items                *jsonSchema
+	enum                 []any
+	constValue           any
+	hasConst             bool
+	minimum, maximum     *float64
+	exclusiveMin         *float64
+	exclusiveMax         *float64
+	minLength, maxLength *int
+	pattern              *regexp.Regexp
+	minItems, maxItems   *int
+	allOf, anyOf, oneOf  []*jsonSchema
+	not                  *jsonSchema
+}
+
+// Keywords that only describe the schema
+var jsonSchemaAnnotations = []string{"$schema", "$id", "$comment", "title", "description", "default", "examples", "format", "deprecated", "readOnly", "writeOnly"}
+
+// Compiled schemas are cached by their JSON, as the same schema is used for
+// every call the task makes
+var jsonSchemaCache sync.Map
+
+func compileJSONSchemaCached(raw json.RawMessage) (*jsonSchema, error) {
+	if s, ok := jsonSchemaCache.Load(string(raw)); ok {
+		return s.(*jsonSchema), nil
+	}
+
+	var def any
+	if err := decodeJSON(raw, &def); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidResponseSchema, err)
+	}
+	s, err := compileJSONSchema(def, "#")
+	if err != nil {
+		return nil, err
+	}
+
+	jsonSchemaCache.Store(string(raw), s)
+	return s, nil
+}
+
+func compileJSONSchema(def any, path string) (*jsonSchema, error) {
+	if b, ok := def.(bool); ok {
+		// true allows anything, false allows nothing
+		s := &jsonSchema{}
+		if !b {
+			s.not = &jsonSchema{}
+		}
+		return s, nil
+	}
+
+	m, ok := def.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s must be an object or boolean", ErrInvalidResponseSchema, path)
+	}
+
+	s := &jsonSchema{}
+	for k, v := range m {
+		p := path + "/" + k
+		var err error
+		switch k {
+		case "type":
+			s.types, err = schemaStrings(v, p)
+		case "properties":
+			props, ok := v.(map[string]any)
+			if !ok {
+				return nil, fmt.Errorf("%w: %s must be an object", ErrInvalidResponseSchema, p)
+			}
+			s.properties = make(map[string]*jsonSchema, len(props))
+			for name, prop := range props {
+				if s.properties[name], err = compileJSONSchema(prop, p+"/"+name); err != nil {
+					return nil, err
+				}
+			}
+		case "required":
+			s.required, err = schemaStrings(v, p)
+		case "additionalProperties":
+			if b, ok := v.(bool); ok {
+				s.noAdditional = !b
+			} else {
+				s.additionalProperties, err = compileJSONSchema(v, p)
+			}
+		case "items":
+			s.items, err = compileJSONSchema(v, p)
+		case "enum":
+			values, ok := v.([]any)
+			if !ok {
+				return nil, fmt.Errorf("%w: %s must be an array", ErrInvalidResponseSchema, p)
+			}
+			s.enum = values
+		case "const":
+			s.constValue, s.hasConst = v, true
+		case "minimum":
+			s.minimum, err = schemaNumber(v, p)
+		case "maximum":
+			s.maximum, err = schemaNumber(v, p)
+		case "exclusiveMinimum":
+			s.exclusiveMin, err = schemaNumber(v, p)
+		case "exclusiveMaximum":
+			s.exclusiveMax, err = schemaNumber(v, p)
+		case "minLength":
+			s.minLength, err = schemaInt(v, p)
+		case "maxLength":
+			s.maxLength, err = schemaInt(v, p)
+		case "minItems":
+			s.minItems, err = schemaInt(v, p)
+		case "maxItems":
+			s.maxItems, err = schemaInt(v, p)
+		case "pattern":
+			str, ok := v.(string)
+			if !ok {
+				return nil, fmt.Errorf("%w: %s must be a string", ErrInvalidResponseSchema, p)
+			}
+			if s.pattern, err = regexp.Compile(str); err != nil {
+				err = fmt.Errorf("%w: %s: %w", ErrInvalidResponseSchema, p, err)
+			}
+		case "allOf", "anyOf", "oneOf":
+			var list []*jsonSchema
+			if list, err = schemaList(v, p); err != nil {
+				return nil, err
+			}
+			switch k {
+			case "allOf":
+				s.allOf = list
+			case "anyOf":
+				s.anyOf = list
+			default:
+				s.oneOf = list
+			}
+		case "not":
+			s.not, err = compileJSONSchema(v, p)
+		default:
+			if !slices.Contains(jsonSchemaAnnotations, k) {
+				return nil, fmt.Errorf("%w: %s is not supported", ErrInvalidResponseSchema, p)
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return s, nil
+}
+
+func schemaStrings(v any, path string) ([]string, error) {
+	switch t := v.(type) {
+	case string:
+		return []string{t}, nil
+	case []any:
+		values := make([]string, 0, len(t))
+		for _, i := range t {
+			str, ok := i.(string)
+			if !ok {
+				return nil, fmt.Errorf("%w: %s must be strings", ErrInvalidResponseSchema, path)
+			}
+			values = append(values, str)
+		}
+		return values, nil
+	}
+	return nil, fmt.Errorf("%w: %s must be a string or array of strings", ErrInvalidResponseSchema, path)
+}
+
+func schemaNumber(v any, path string) (*float64, error) {
+	f, ok := toFloat(v)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s must be a number", ErrInvalidResponseSchema, path)
+	}
+	return &f, nil
+}
+
+func schemaInt(v any, path string) (*int, error) {
+	f, ok := toFloat(v)
+	if !ok || f < 0 || f != math.Trunc(f) {
+		return nil, fmt.Errorf("%w: %s must be a non-negative integer", ErrInvalidResponseSchema, path)
+	}
+	i := int(f)
+	return &i, nil
+}
+
+func schemaList(v any, path string) ([]*jsonSchema, error) {
+	defs, ok := v.([]any)
+	if !ok || len(defs) == 0 {
+		return nil, fmt.Errorf("%w: %s must be a non-empty array", ErrInvalidResponseSchema, path)
+	}
+	list := make([]*jsonSchema, 0, len(defs))
+	for i, d := range defs {
+		s, err := compileJSONSchema(d, fmt.Sprintf("%s/%d", path, i))
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, s)
+	}
+	return list, nil
+}
+
+// Checks the response body is JSON matching the schema
+func checkResponseSchema(raw json.RawMessage, body []byte) error {
+	s, err := compileJSONSchemaCached(raw)
+	if err != nil {
+		return err
+	}
+
+	var value any
+	if err := decodeJSON(body, &value); err != nil {
+		return fmt.Errorf("%w: response is not json: %w", ErrResponseSchemaMismatch, err)
+	}
+
+	if errs := s.validate(value, "#"); len(errs) > 0 {
+		return fmt.Errorf("%w: %s", ErrResponseSchemaMismatch, strings.Join(errs, "; "))
+	}
+
+	return nil
+}
+
+// The JSON type of a decoded value
+func jsonType(v any) string {
+	switch n := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	case json.Number:
+		if _, err := n.Int64(); err == nil {
+			return "integer"
+		}
+	}
+	if f, ok := toFloat(v); ok && f == math.Trunc(f) && !math.IsInf(f, 0) {
+		return "integer"
+	}
+	return "number"
+}
+
+// Validates the value, returning a description of each problem found
+func (s *jsonSchema) validate(v any, path string) []string {
+	errs := make([]string, 0)
+	fail := func(format string, args ...any) {
+		errs = append(errs, path+": "+fmt.Sprintf(format, args...))
+	}
+
+	if len(s.types) > 0 {
+		t := jsonType(v)
+		if !slices.Contains(s.types, t) && (t != "integer" || !slices.Contains(s.types, "number")) {
+			fail("expected %s, got %s", strings.Join(s.types, " or "), t)
+			// Nothing else can be checked against the wrong type
+			return errs
+		}
+	}
+
+	if len(s.enum) > 0 && !slices.ContainsFunc(s.enum, func(e any) bool { return valuesEqual(v, e) }) {
+		fail("must be one of %v", s.enum)
+	}
+	if s.hasConst && !valuesEqual(v, s.constValue) {
+		fail("must be %v", s.constValue)
+	}
+
+	switch value := v.(type) {
+	case map[string]any:
+		for _, r := range s.required {
+			if _, ok := value[r]; !ok {
+				fail("missing required property %q", r)
+			}
+		}
+		for _, k := range slices.Sorted(maps.Keys(value)) {
+			item := value[k]
+			if prop, ok := s.properties[k]; ok {
+				errs = append(errs, prop.validate(item, path+"/"+k)...)
+			} else if s.noAdditional {
+				fail("property %q is not allowed", k)
+			} else if s.additionalProperties != nil {
+				errs = append(errs, s.additionalProperties.validate(item, path+"/"+k)...)
+			}
+		}
+	case []any:
+		if s.minItems != nil && len(value) < *s.minItems {
+			fail("must have at least %d items", *s.minItems)
+		}
+		if s.maxItems != nil && len(value) > *s.maxItems {
+			fail("must have at most %d items", *s.maxItems)
+		}
+		if s.items != nil {
+			for i, item := range value {
+				errs = append(errs, s.items.validate(item, fmt.Sprintf("%s/%d", path, i))...)
+			}
+		}
+	case string:
+		length := utf8.RuneCountInString(value)
+		if s.minLength != nil && length < *s.minLength {
+			fail("must be at least %d characters", *s.minLength)
+		}
+		if s.maxLength != nil && length > *s.maxLength {
+			fail("must be at most %d characters", *s.maxLength)
+		}
+		if s.pattern != nil && !s.pattern.MatchString(value) {
+			fail("must match %s", s.pattern)
+		}
+	default:
+		if f, ok := toFloat(v); ok {
+			if s.minimum != nil && f < *s.minimum {
+				fail("must be at least %v", *s.minimum)
+			}
+			if s.maximum != nil && f > *s.maximum {
+				fail("must be at most %v", *s.maximum)
+			}
+			if s.exclusiveMin != nil && f <= *s.exclusiveMin {
+				fail("must be more than %v", *s.exclusiveMin)
+			}
+			if s.exclusiveMax != nil && f >= *s.exclusiveMax {
+				fail("must be less than %v", *s.exclusiveMax)
+			}
+		}
+	}
+
+	for _, sub := range s.allOf {
+		errs = append(errs, sub.validate(v, path)...)
+	}
+	if len(s.anyOf) > 0 && !slices.ContainsFunc(s.anyOf, func(sub *jsonSchema) bool { return len(sub.validate(v, path)) == 0 }) {
+		fail("must match at least one schema in anyOf")
+	}
+	if len(s.oneOf) > 0 {
+		matches := 0
+		for _, sub := range s.oneOf {
+			if len(sub.validate(v, path)) == 0 {
+				matches++
+			}
+		}
+		if matches != 1 {
+			fail("must match exactly one schema in oneOf, matched %d", matches)
+		}
+	}
+	if s.not != nil && len(s.not.validate(v, path)) == 0 {
+		fail("must not match the schema in not")
+	}
+
+	return errs
+}
@@ -0,0 +1,63 @@
+/*
+ * Copyright 2025 Simon Emms <simon@simonemms.com>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package workflow
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// The metadata key used to label a task, eg for cost attribution
+const labelsMetadata = "labels"
+
+// Gets the task's labels from its metadata. Values must be strings, numbers
+// or booleans.
+func taskLabels(key string, metadata map[string]any) (map[string]string, error) {
+	v, ok := metadata[labelsMetadata]
+	if !ok || v == nil {
+		return nil, nil
+	}
+
+	m, ok := v.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidLabels, key)
+	}
+
+	labels := make(map[string]string, len(m))
+	for k, value := range m {
+		switch value.(type) {
+		case string, bool, int, int64, uint64, float64:
+			labels[k] = fmt.Sprint(value)
+		default:
+			return nil, fmt.Errorf("%w: %s.%s must be a string, number or boolean", ErrInvalidLabels, key, k)
+		}
+	}
+
+	return labels, nil
+}
+
+// The labels as a single line, eg "team=payments, tenant=acme", for the
+// activity's summary in the Temporal UI
+func labelSummary(labels map[string]string) string {
+	pairs := make([]string, 0, len(labels))
+	for _, k := range slices.Sorted(maps.Keys(labels)) {
+		pairs = append(pairs, k+"="+labels[k])
+	}
+	return strings.Join(pairs, ", ")
+}
@@ -0,0 +1,74 @@
+/*
+ * Copyright 2025 Simon Emms <simon@simonemms.com>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package workflow
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"net/http"
+	"time"
+
+	"go.temporal.io/sdk/activity"
+	"go.temporal.io/sdk/workflow"
+)
+
+// Custom metrics emitted for each task run by a workflow. These are sent via
+// the metrics handler configured on the Temporal client.
+const (
+	MetricTaskExecutions = "tsw_task_executions"
+	MetricTaskSuccesses  = "tsw_task_successes"
+	MetricTaskFailures   = "tsw_task_failures"
+	MetricTaskLatency    = "tsw_task_latency"
+
+	// Time to get the response headers from an HTTP call
+	MetricHTTPCallLatency = "tsw_http_call_latency"
+)
+
+// Records the execution, outcome and latency of a task. The workflow metrics
+// handler is replay-aware so nothing is emitted when a workflow is replayed.
+func recordTaskMetrics(ctx workflow.Context, task TemporalWorkflowTask, start time.Time, err error) {
+	tags := make(map[string]string, len(task.Labels)+2)
+	maps.Copy(tags, task.Labels)
+	tags["task_key"] = task.Key
+	tags["task_type"] = task.Type
+
+	handler := workflow.GetMetricsHandler(ctx).WithTags(tags)
+
+	handler.Counter(MetricTaskExecutions).Inc(1)
+	if err != nil {
+		handler.Counter(MetricTaskFailures).Inc(1)
+	} else {
+		handler.Counter(MetricTaskSuccesses).Inc(1)
+	}
+	handler.Timer(MetricTaskLatency).Record(workflow.Now(ctx).Sub(start))
+}
+
+// Records the latency of an HTTP call, tagged by the host and status class
+// (eg, 2xx). Failed calls have a status class of "error". The activity's
+// metrics handler is a no-op unless metrics are configured on the client.
+func recordHTTPCallLatency(ctx context.Context, host string, resp *http.Response, latency time.Duration) {
+	statusClass := "error"
+	if resp != nil {
+		statusClass = fmt.Sprintf("%dxx", resp.StatusCode/100)
+	}
+
+	activity.GetMetricsHandler(ctx).WithTags(map[string]string{
+		"host":         host,
+		"status_class": statusClass,
+	}).Timer(MetricHTTPCallLatency).Record(latency)
+}
@@ -0,0 +1,107 @@
+/*
+ * Copyright 2025 Simon Emms <simon@simonemms.com>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package workflow
+
+import (
+	"fmt"
+	"maps"
+	"strings"
+
+	"go.temporal.io/sdk/workflow"
+)
+
+// The metadata key used to rename a task's output
+const outputKeyMetadata = "outputKey"
+
+// The metadata key used to let the workflow continue if a task fails
+const continueOnErrorMetadata = "continueOnError"
+
+// Gets the key a task's output is written to. This defaults to the task's
+// key, but can be set in the metadata so the output doesn't depend on the
+// task's name.
+func taskOutputKey(key string, metadata map[string]any) (string, error) {
+	v, ok := metadata[outputKeyMetadata]
+	if !ok || v == nil {
+		return key, nil
+	}
+
+	outputKey, ok := v.(string)
+	if !ok || outputKey == "" {
+		return "", fmt.Errorf("%w: %s", ErrInvalidOutputKey, key)
+	}
+
+	return outputKey, nil
+}
+
+// Renames the outputs the task writes under its key. Tasks with more than one
+// output, such as a fork's branches, write these as "<key>_<name>" so are
+// renamed to "<outputKey>_<name>".
+func withOutputKey(task TemporalWorkflowFunc, key, outputKey string) TemporalWorkflowFunc {
+	return func(ctx workflow.Context, data *Variables, output map[string]OutputType) error {
+		o := make(map[string]OutputType)
+		err := task(ctx, data, o)
+
+		renamed := make(map[string]OutputType, len(o))
+		for k, v := range o {
+			if k == key {
+				k = outputKey
+			} else if suffix, ok := strings.CutPrefix(k, key+"_"); ok {
+				k = outputKey + "_" + suffix
+			}
+			renamed[k] = v
+		}
+		maps.Copy(output, renamed)
+
+		return err
+	}
+}
+
+// Checks if the workflow continues if the task fails. Defaults to false.
+func taskContinueOnError(key string, metadata map[string]any) (bool, error) {
+	v, ok := metadata[continueOnErrorMetadata]
+	if !ok || v == nil {
+		return false, nil
+	}
+
+	continueOnError, ok := v.(bool)
+	if !ok {
+		return false, fmt.Errorf("%w: %s", ErrInvalidContinueOnError, key)
+	}
+
+	return continueOnError, nil
+}
+
+// Logs a failed task's error and writes it to the output, rather than failing
+// the workflow. Cancellation isn't caught, so a cancelled workflow still stops.
+func withContinueOnError(task TemporalWorkflowFunc, key string) TemporalWorkflowFunc {
+	return func(ctx workflow.Context, data *Variables, output map[string]OutputType) error {
+		err := task(ctx, data, output)
+		if err == nil || ctx.Err() != nil {
+			return err
+		}
+
+		workflow.GetLogger(ctx).Warn("Task failed - continuing", "task", key, "error", err)
+		setTaskStatus(data, key, TaskStatusFaulted)
+
+		output[key] = OutputType{
+			Type: ErrorResultType,
+			Data: caughtError(err),
+		}
+
+		return nil
+	}
+}
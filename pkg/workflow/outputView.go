@@ -0,0 +1,117 @@
+/*
+ * Copyright 2025 Simon Emms <simon@simonemms.com>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package workflow
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/itchyny/gojq"
+	"github.com/serverlessworkflow/sdk-go/v3/model"
+	"gopkg.in/yaml.v3"
+)
+
+// OutputViews maps a view's name to the compiled jq expression that transforms
+// the workflow's output. The view is chosen when the workflow is started.
+type OutputViews map[string]*gojq.Code
+
+// WithOutputViews sets the views the workflow's output can be returned as
+func WithOutputViews(views OutputViews) Option {
+	return func(w *Workflow) {
+		w.outputViews = views
+	}
+}
+
+// LoadOutputViewsFromFile loads a YAML map of view names to jq expressions,
+// eg summary: ${ { user: .getUser.data.name } }
+func LoadOutputViewsFromFile(file string) (OutputViews, error) {
+	data, err := os.ReadFile(filepath.Clean(file))
+	if err != nil {
+		return nil, fmt.Errorf("error loading output views file: %w", err)
+	}
+
+	var cfg map[string]string
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("error converting output views yaml: %w", err)
+	}
+
+	views := make(OutputViews, len(cfg))
+	for name, expression := range cfg {
+		code, err := parseJQ(model.SanitizeExpr(expression), "outputViews."+name)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidOutputView, err)
+		}
+		views[name] = code
+	}
+
+	return views, nil
+}
+
+// Gets the view chosen in the input. If none is chosen, nil is returned and
+// the output is returned as-is.
+func (t *TemporalWorkflow) outputView(input HTTPData) (*gojq.Code, error) {
+	v, ok := input[OutputViewKey]
+	if !ok || v == nil {
+		return nil, nil
+	}
+
+	name := fmt.Sprint(v)
+	code, ok := t.OutputViews[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownOutputView, name)
+	}
+
+	return code, nil
+}
+
+// Transforms the output with the view. A single result is returned as-is,
+// with multiple results returned as an array.
+func applyOutputView(code *gojq.Code, output map[string]OutputType) (any, error) {
+	// The view sees the output as it's returned, eg .getUser.data
+	b, err := json.Marshal(output)
+	if err != nil {
+		return nil, fmt.Errorf("error converting output to json: %w", err)
+	}
+	var data map[string]any
+	if err := decodeJSON(b, &data); err != nil {
+		return nil, fmt.Errorf("error converting output from json: %w", err)
+	}
+
+	results := make([]any, 0)
+	iter := code.Run(data)
+	for {
+		v, ok := iter.Next()
+		if !ok {
+			break
+		}
+		if err, ok := v.(error); ok {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidOutputView, err)
+		}
+		results = append(results, v)
+	}
+
+	switch len(results) {
+	case 0:
+		return nil, nil
+	case 1:
+		return results[0], nil
+	default:
+		return results, nil
+	}
+}
@@ -0,0 +1,84 @@
+/*
+ * Copyright 2025 Simon Emms <simon@simonemms.com>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package workflow
+
+import (
+	"fmt"
+
+	"go.temporal.io/sdk/workflow"
+)
+
+const (
+	// Signal to pause a workflow before its next task
+	PauseSignal = "__pause"
+
+	// Signal to resume a paused workflow
+	ResumeSignal = "__resume"
+)
+
+type pause struct {
+	paused bool
+}
+
+type pauseKey struct{}
+
+// Listens for the pause and resume signals for the rest of the workflow. The
+// state is set on the context so inline workflows pause too.
+func enablePause(ctx workflow.Context) workflow.Context {
+	p := &pause{}
+	logger := workflow.GetLogger(ctx)
+
+	workflow.Go(ctx, func(ctx workflow.Context) {
+		selector := workflow.NewSelector(ctx)
+		selector.AddReceive(workflow.GetSignalChannel(ctx, PauseSignal), func(c workflow.ReceiveChannel, more bool) {
+			c.Receive(ctx, nil)
+			logger.Info("Workflow paused - waiting for the resume signal", "signal", ResumeSignal)
+			p.paused = true
+		})
+		selector.AddReceive(workflow.GetSignalChannel(ctx, ResumeSignal), func(c workflow.ReceiveChannel, more bool) {
+			c.Receive(ctx, nil)
+			logger.Info("Workflow resumed")
+			p.paused = false
+		})
+
+		selector.AddReceive(ctx.Done(), func(c workflow.ReceiveChannel, more bool) {})
+
+		for ctx.Err() == nil {
+			selector.Select(ctx)
+		}
+	})
+
+	return workflow.WithValue(ctx, pauseKey{}, p)
+}
+
+// Blocks until the workflow's resumed, if it's paused. A running task isn't
+// interrupted, so this is checked before each task.
+func waitIfPaused(ctx workflow.Context, key string) error {
+	p, ok := ctx.Value(pauseKey{}).(*pause)
+	if !ok || p == nil || !p.paused {
+		return nil
+	}
+
+	workflow.GetLogger(ctx).Info("Workflow paused before task", "name", key)
+	if err := workflow.Await(ctx, func() bool {
+		return !p.paused
+	}); err != nil {
+		return fmt.Errorf("error waiting for resume: %w", err)
+	}
+
+	return nil
+}
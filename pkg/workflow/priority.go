@@ -0,0 +1,78 @@
+/*
+ * Copyright 2025 Simon Emms <simon@simonemms.com>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package workflow
+
+import (
+	"fmt"
+	"math"
+
+	"go.temporal.io/sdk/temporal"
+)
+
+// The metadata key used to set the priority key on the document or a task
+const priorityKeyMetadata = "priorityKey"
+
+// Gets the priority key from the metadata, or 0 if not set
+func priorityKeyFromMetadata(metadata map[string]any) (int, error) {
+	v, ok := metadata[priorityKeyMetadata]
+	if !ok || v == nil {
+		return 0, nil
+	}
+
+	var key int
+	switch n := v.(type) {
+	case int:
+		key = n
+	case int64:
+		key = int(n)
+	case uint64:
+		if n > math.MaxInt32 {
+			return 0, fmt.Errorf("%w: %v", ErrInvalidPriorityKey, v)
+		}
+		key = int(n)
+	case float64:
+		if n != math.Trunc(n) {
+			return 0, fmt.Errorf("%w: %v", ErrInvalidPriorityKey, v)
+		}
+		key = int(n)
+	default:
+		return 0, fmt.Errorf("%w: %v", ErrInvalidPriorityKey, v)
+	}
+
+	if key < 1 {
+		return 0, fmt.Errorf("%w: %v", ErrInvalidPriorityKey, v)
+	}
+
+	return key, nil
+}
+
+// Priority returns the priority for the workflows. The priority key set in
+// the document's metadata takes precedence over the one set by the options.
+// This can be used in the StartWorkflowOptions when starting a workflow.
+func (w *Workflow) Priority() (temporal.Priority, error) {
+	key, err := priorityKeyFromMetadata(w.wf.Document.Metadata)
+	if err != nil {
+		return temporal.Priority{}, fmt.Errorf("error getting workflow priority: %w", err)
+	}
+	if key == 0 {
+		key = w.priorityKey
+	}
+
+	return temporal.Priority{
+		PriorityKey: key,
+	}, nil
+}
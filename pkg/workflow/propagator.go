@@ -0,0 +1,139 @@
+/*
+ * Copyright 2025 Simon Emms <simon@simonemms.com>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package workflow
+
+import (
+	"context"
+	"slices"
+	"strings"
+
+	commonpb "go.temporal.io/api/common/v1"
+	"go.temporal.io/sdk/converter"
+	"go.temporal.io/sdk/workflow"
+)
+
+// Prefix of the Temporal headers carrying propagated variables
+const propagatedHeaderPrefix = "tsw-var-"
+
+type (
+	propagatedValuesKey    struct{}
+	propagatedVariablesKey struct{}
+)
+
+// ContextPropagator carries the selected variables across workflow boundaries
+// in Temporal headers - from the client starting a workflow, to its
+// activities and any child workflows. This keeps values such as correlation
+// IDs and tenants without passing them manually.
+type ContextPropagator struct {
+	keys []string
+}
+
+var _ workflow.ContextPropagator = &ContextPropagator{}
+
+// NewContextPropagator propagates the named variables. This must be set on
+// the client of both the worker and anything starting workflows.
+func NewContextPropagator(keys []string) *ContextPropagator {
+	return &ContextPropagator{
+		keys: keys,
+	}
+}
+
+// WithPropagatedValues sets variables on the context to be sent when a
+// workflow is started. Only the propagator's keys are sent.
+func WithPropagatedValues(ctx context.Context, values map[string]any) context.Context {
+	return context.WithValue(ctx, propagatedValuesKey{}, values)
+}
+
+// Gets the variables received in the workflow's headers
+func propagatedValues(ctx workflow.Context) map[string]any {
+	if values, ok := ctx.Value(propagatedValuesKey{}).(map[string]any); ok {
+		return values
+	}
+	return nil
+}
+
+// Sets the workflow's current variables to be sent to activities and child
+// workflows
+func withPropagatedVariables(ctx workflow.Context, vars *Variables) workflow.Context {
+	return workflow.WithValue(ctx, propagatedVariablesKey{}, vars)
+}
+
+func (p *ContextPropagator) Inject(ctx context.Context, writer workflow.HeaderWriter) error {
+	values, _ := ctx.Value(propagatedValuesKey{}).(map[string]any)
+	return p.inject(values, writer)
+}
+
+func (p *ContextPropagator) InjectFromWorkflow(ctx workflow.Context, writer workflow.HeaderWriter) error {
+	if vars, ok := ctx.Value(propagatedVariablesKey{}).(*Variables); ok && vars != nil {
+		return p.inject(vars.Data, writer)
+	}
+	return p.inject(propagatedValues(ctx), writer)
+}
+
+func (p *ContextPropagator) Extract(ctx context.Context, reader workflow.HeaderReader) (context.Context, error) {
+	values, err := p.extract(reader)
+	if err != nil {
+		return nil, err
+	}
+	return context.WithValue(ctx, propagatedValuesKey{}, values), nil
+}
+
+func (p *ContextPropagator) ExtractToWorkflow(ctx workflow.Context, reader workflow.HeaderReader) (workflow.Context, error) {
+	values, err := p.extract(reader)
+	if err != nil {
+		return nil, err
+	}
+	return workflow.WithValue(ctx, propagatedValuesKey{}, values), nil
+}
+
+func (p *ContextPropagator) inject(values map[string]any, writer workflow.HeaderWriter) error {
+	for _, key := range p.keys {
+		v, ok := values[key]
+		if !ok {
+			continue
+		}
+
+		payload, err := converter.GetDefaultDataConverter().ToPayload(v)
+		if err != nil {
+			return err
+		}
+		writer.Set(propagatedHeaderPrefix+key, payload)
+	}
+	return nil
+}
+
+func (p *ContextPropagator) extract(reader workflow.HeaderReader) (map[string]any, error) {
+	values := map[string]any{}
+	err := reader.ForEachKey(func(header string, payload *commonpb.Payload) error {
+		key, ok := strings.CutPrefix(header, propagatedHeaderPrefix)
+		if !ok || !slices.Contains(p.keys, key) {
+			return nil
+		}
+
+		var v any
+		if err := converter.GetDefaultDataConverter().FromPayload(payload, &v); err != nil {
+			return err
+		}
+		values[key] = v
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return values, nil
+}
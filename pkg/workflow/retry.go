@@ -0,0 +1,152 @@
+/*
+ * Copyright 2025 Simon Emms <simon@simonemms.com>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package workflow
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"go.temporal.io/sdk/client"
+	"go.temporal.io/sdk/temporal"
+	"gopkg.in/yaml.v3"
+)
+
+// The metadata key used to set the workflow retry policy on the document
+const retryMetadata = "retry"
+
+type RetryConfig struct {
+	BackoffCoefficient     float64       `yaml:"backoffCoefficient"`
+	InitialInterval        time.Duration `yaml:"initialInterval"`
+	MaximumAttempts        int32         `yaml:"maximumAttempts"`
+	MaximumInterval        time.Duration `yaml:"maximumInterval"`
+	NonRetryableErrorTypes []string      `yaml:"nonRetryableErrorTypes"`
+}
+
+// RetryPolicy converts the config to a Temporal retry policy. A nil config
+// returns a nil policy so Temporal's defaults are used.
+func (r *RetryConfig) RetryPolicy() *temporal.RetryPolicy {
+	if r == nil {
+		return nil
+	}
+
+	return &temporal.RetryPolicy{
+		BackoffCoefficient:     r.BackoffCoefficient,
+		InitialInterval:        r.InitialInterval,
+		MaximumAttempts:        r.MaximumAttempts,
+		MaximumInterval:        r.MaximumInterval,
+		NonRetryableErrorTypes: r.NonRetryableErrorTypes,
+	}
+}
+
+// WithWorkflowRetry sets the default retry policy for the whole workflow. Any
+// retry policy set in the document's metadata takes precedence.
+func WithWorkflowRetry(cfg *RetryConfig) Option {
+	return func(w *Workflow) {
+		w.workflowRetry = cfg
+	}
+}
+
+func LoadRetryConfigFromFile(file string) (*RetryConfig, error) {
+	data, err := os.ReadFile(filepath.Clean(file))
+	if err != nil {
+		return nil, fmt.Errorf("error loading retry file: %w", err)
+	}
+
+	var cfg RetryConfig
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("error converting retry yaml: %w", err)
+	}
+
+	return &cfg, nil
+}
+
+// RetryPolicy returns the retry policy for a whole workflow run. This is
+// distinct from the activity retries - a retried workflow starts again from
+// scratch. Returns nil if no workflow retries are configured.
+func (w *Workflow) RetryPolicy() (*temporal.RetryPolicy, error) {
+	cfg, err := retryConfigFromMetadata(w.wf.Document.Metadata)
+	if err != nil {
+		return nil, fmt.Errorf("error reading workflow retry metadata: %w", err)
+	}
+	if cfg == nil {
+		cfg = w.workflowRetry
+	}
+
+	return cfg.RetryPolicy(), nil
+}
+
+// Reads the retry config from the metadata. Returns nil if it's not set.
+func retryConfigFromMetadata(metadata map[string]any) (*RetryConfig, error) {
+	v, ok := metadata[retryMetadata]
+	if !ok || v == nil {
+		return nil, nil
+	}
+
+	// Round-trip the metadata through YAML so durations can be written as strings
+	data, err := yaml.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("error reading retry metadata: %w", err)
+	}
+
+	cfg := &RetryConfig{}
+	if err := yaml.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("error converting retry metadata: %w", err)
+	}
+
+	return cfg, nil
+}
+
+// StartWorkflowOptions returns the options to start the workflows with the
+// workflow's retry policy and priority applied
+func (w *Workflow) StartWorkflowOptions(taskQueue string) (client.StartWorkflowOptions, error) {
+	retryPolicy, err := w.RetryPolicy()
+	if err != nil {
+		return client.StartWorkflowOptions{}, err
+	}
+
+	priority, err := w.Priority()
+	if err != nil {
+		return client.StartWorkflowOptions{}, err
+	}
+
+	return client.StartWorkflowOptions{
+		TaskQueue:   taskQueue,
+		RetryPolicy: retryPolicy,
+		Priority:    priority,
+	}, nil
+}
+
+// Converts the error returned by a workflow so that failures that can never
+// succeed, such as a non-retryable activity error or a broken expression,
+// don't trigger a workflow retry. Temporal only checks the top-level failure
+// so these are re-raised as non-retryable.
+func workflowError(err error) error {
+	var appErr *temporal.ApplicationError
+	if errors.As(err, &appErr) && appErr.NonRetryable() {
+		return temporal.NewNonRetryableApplicationError(err.Error(), appErr.Type(), err)
+	}
+
+	var exprErr *ExpressionError
+	if errors.As(err, &exprErr) {
+		return temporal.NewNonRetryableApplicationError(err.Error(), string(ExpressionErr), err)
+	}
+
+	return err
+}
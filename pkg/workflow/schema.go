@@ -0,0 +1,194 @@
+/*
+ * Copyright 2025 Simon Emms <simon@simonemms.com>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package workflow
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/rs/zerolog/log"
+	"github.com/serverlessworkflow/sdk-go/v3/model"
+)
+
+// The DSL versions that can be loaded
+var supportedDSLVersions = []string{"1.0.0"}
+
+type taskSupport struct {
+	name      string
+	supported bool
+	// The error returned when an unsupported task is used
+	err   *UnsupportedTaskError
+	match func(task *model.TaskItem) bool
+}
+
+// The Serverless Workflow tasks and whether they're implemented. This is the
+// source of truth for both the validation and the supported schema, so update
+// this as tasks are implemented.
+var taskSupportList = []taskSupport{
+	{
+		name:  "call.grpc",
+		err:   ErrUnsupportedCallGRPCTask,
+		match: func(task *model.TaskItem) bool { return task.AsCallGRPCTask() != nil },
+	},
+	{
+		name:      "call.http",
+		supported: true,
+		match:     func(task *model.TaskItem) bool { return task.AsCallHTTPTask() != nil },
+	},
+	{
+		name:  "call.openapi",
+		err:   ErrUnsupportedCallOpenAPITask,
+		match: func(task *model.TaskItem) bool { return task.AsCallOpenAPITask() != nil },
+	},
+	{
+		name:      "do",
+		supported: true,
+		match:     func(task *model.TaskItem) bool { return task.AsDoTask() != nil },
+	},
+	{
+		name:      "emit",
+		supported: true,
+		match:     func(task *model.TaskItem) bool { return task.AsEmitTask() != nil },
+	},
+	{
+		name:  "for",
+		err:   ErrUnsupportedForTask,
+		match: func(task *model.TaskItem) bool { return task.AsForTask() != nil },
+	},
+	{
+		name:      "fork",
+		supported: true,
+		match:     func(task *model.TaskItem) bool { return task.AsForkTask() != nil },
+	},
+	{
+		name:      "listen",
+		supported: true,
+		match:     func(task *model.TaskItem) bool { return task.AsListenTask() != nil },
+	},
+	{
+		name:      "noop",
+		supported: true,
+		match:     isNoopTask,
+	},
+	{
+		name:  "raise",
+		err:   ErrUnsupportedRaiseTask,
+		match: func(task *model.TaskItem) bool { return task.AsRaiseTask() != nil },
+	},
+	{
+		name:  "run",
+		err:   ErrUnsupportedRunTask,
+		match: func(task *model.TaskItem) bool { return task.AsRunTask() != nil },
+	},
+	{
+		name:      "set",
+		supported: true,
+		match:     func(task *model.TaskItem) bool { return task.AsSetTask() != nil },
+	},
+	{
+		name:  "switch",
+		err:   ErrUnsupportedSwitchTask,
+		match: func(task *model.TaskItem) bool { return task.AsSwitchTask() != nil },
+	},
+	{
+		name:      "try",
+		supported: true,
+		match:     func(task *model.TaskItem) bool { return task.AsTryTask() != nil },
+	},
+	{
+		name:      "wait",
+		supported: true,
+		match:     func(task *model.TaskItem) bool { return task.AsWaitTask() != nil },
+	},
+}
+
+// SupportedTask describes whether a Serverless Workflow task is implemented
+type SupportedTask struct {
+	Name      string `json:"name"`
+	Supported bool   `json:"supported"`
+	Code      string `json:"code,omitempty"`
+	Hint      string `json:"hint,omitempty"`
+}
+
+// Schema is a machine-readable description of the subset of Serverless
+// Workflow that's implemented, such as for validation in authoring tools
+type Schema struct {
+	DSL   []string        `json:"dsl"`
+	Tasks []SupportedTask `json:"tasks"`
+}
+
+// SupportedSchema returns the DSL versions and tasks that can be used. This
+// is the same data used by Validate.
+func SupportedSchema() Schema {
+	tasks := make([]SupportedTask, 0, len(taskSupportList))
+	for _, t := range taskSupportList {
+		task := SupportedTask{
+			Name:      t.name,
+			Supported: t.supported,
+		}
+		if t.err != nil {
+			task.Code = t.err.Code
+			task.Hint = t.err.Hint
+		}
+		tasks = append(tasks, task)
+	}
+
+	return Schema{
+		DSL:   append([]string{}, supportedDSLVersions...),
+		Tasks: tasks,
+	}
+}
+
+// Checks that each task name is in the support list
+func validateTaskNames(names []string) error {
+	for _, name := range names {
+		if !slices.ContainsFunc(taskSupportList, func(t taskSupport) bool { return t.name == name }) {
+			return fmt.Errorf("%w: %s", ErrUnknownTaskName, name)
+		}
+	}
+	return nil
+}
+
+// Validation of the schema is handled separately. This validates that there is
+// nothing used we've not implemented. This should reduce over time.
+//
+// Unsupported tasks in the allow list pass validation with a warning. They
+// aren't run by the workflow. Unsupported tasks with a custom handler pass.
+func validateTaskSupported(task *model.TaskItem, allow []string) error {
+	if doTask := task.AsDoTask(); doTask != nil {
+		// Do task - iterate through these
+		for _, t := range *doTask.Do {
+			if err := validateTaskSupported(t, allow); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, t := range taskSupportList {
+		if !t.supported && t.match(task) {
+			if _, builder := customTaskHandler(task); builder != nil {
+				continue
+			}
+			if slices.Contains(allow, t.name) {
+				log.Warn().Str("key", task.Key).Str("task", t.name).Msg("EXPERIMENTAL - task is not supported and will not be run")
+				continue
+			}
+			return t.err.withKey(task.Key)
+		}
+	}
+	return nil
+}
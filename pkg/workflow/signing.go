@@ -0,0 +1,256 @@
+/*
+ * Copyright 2025 Simon Emms <simon@simonemms.com>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package workflow
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha1" //nolint:gosec // Some webhooks still sign with SHA-1
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"os"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	"github.com/aws/aws-sdk-go-v2/config"
+)
+
+const defaultHMACHeader = "X-Signature"
+
+// SigningPolicy signs HTTP requests just before they're sent. These are
+// named in the document's "use.signing" and used by a call http's "sign".
+type SigningPolicy struct {
+	HMAC  *HMACSigning  `json:"hmac,omitempty"`
+	SigV4 *SigV4Signing `json:"sigv4,omitempty"`
+}
+
+// HMACSigning signs the request body with a shared secret
+type HMACSigning struct {
+	// sha1, sha256 or sha512 - defaults to sha256
+	Algorithm string `json:"algorithm,omitempty"`
+	// hex or base64 - defaults to hex
+	Encoding string `json:"encoding,omitempty"`
+	// Header the signature is sent in - defaults to X-Signature
+	Header string `json:"header,omitempty"`
+	// Added before the signature, eg "sha256="
+	Prefix string `json:"prefix,omitempty"`
+	// Interpolated when the request is signed, eg "{{ .TSW_WEBHOOK_SECRET }}"
+	Secret string `json:"secret"`
+}
+
+// SigV4Signing signs the request for AWS, using the credentials from the AWS
+// SDK's default chain
+type SigV4Signing struct {
+	Region  string `json:"region"`
+	Service string `json:"service"`
+}
+
+// Find the signing policies in the raw workflow definition, keyed by name
+func findSigningPolicies(doc any) (map[string]*SigningPolicy, error) {
+	d, ok := doc.(map[string]any)
+	if !ok {
+		return nil, nil
+	}
+	use, ok := d["use"].(map[string]any)
+	if !ok || use["signing"] == nil {
+		return nil, nil
+	}
+
+	b, err := json.Marshal(use["signing"])
+	if err != nil {
+		return nil, fmt.Errorf("error converting signing policies to json: %w", err)
+	}
+
+	var policies map[string]*SigningPolicy
+	if err := json.Unmarshal(b, &policies); err != nil {
+		return nil, fmt.Errorf("error parsing signing policies: %w", err)
+	}
+
+	for name, p := range policies {
+		if p == nil || (p.HMAC == nil) == (p.SigV4 == nil) {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidSigningPolicy, name)
+		}
+		if p.HMAC != nil {
+			if _, err := p.HMAC.hash(); err != nil {
+				return nil, fmt.Errorf("%w: %s", err, name)
+			}
+			if !slices.Contains([]string{"", "hex", "base64"}, p.HMAC.Encoding) {
+				return nil, fmt.Errorf("%w: unknown hmac encoding %s: %s", ErrInvalidSigningPolicy, p.HMAC.Encoding, name)
+			}
+		}
+		if p.SigV4 != nil && (p.SigV4.Region == "" || p.SigV4.Service == "") {
+			return nil, fmt.Errorf("%w: sigv4 must set region and service: %s", ErrInvalidSigningPolicy, name)
+		}
+	}
+
+	return policies, nil
+}
+
+// Sets the signing policy on each call http that names one
+func resolveSigningPolicies(exts map[string]*CallHTTPExtensions, policies map[string]*SigningPolicy) error {
+	for key, ext := range exts {
+		if ext.With.Sign == "" {
+			continue
+		}
+
+		policy, ok := policies[ext.With.Sign]
+		if !ok {
+			return fmt.Errorf("%w: %s in task %s", ErrUnknownSigningPolicy, ext.With.Sign, key)
+		}
+		ext.SigningPolicy = policy
+	}
+	return nil
+}
+
+// Signs the final request. This must be called once everything has been
+// interpolated so that the signature covers what's sent.
+func (p *SigningPolicy) sign(req *http.Request, vars *Variables, now time.Time) error {
+	switch {
+	case p.HMAC != nil:
+		return p.HMAC.sign(req, vars)
+	case p.SigV4 != nil:
+		return p.SigV4.sign(req, now)
+	}
+	return ErrInvalidSigningPolicy
+}
+
+func (h *HMACSigning) hash() (func() hash.Hash, error) {
+	switch h.Algorithm {
+	case "sha1":
+		return sha1.New, nil
+	case "", "sha256":
+		return sha256.New, nil
+	case "sha512":
+		return sha512.New, nil
+	}
+	return nil, fmt.Errorf("%w: unknown hmac algorithm %s", ErrInvalidSigningPolicy, h.Algorithm)
+}
+
+func (h *HMACSigning) sign(req *http.Request, vars *Variables) error {
+	secret, err := parseCallField(h.Secret, "sign.hmac.secret", vars)
+	if err != nil {
+		return err
+	}
+
+	fn, err := h.hash()
+	if err != nil {
+		return err
+	}
+
+	mac := hmac.New(fn, []byte(secret))
+	if err := hashRequestBody(req, mac); err != nil {
+		return err
+	}
+
+	var signature string
+	if h.Encoding == "base64" {
+		signature = base64.StdEncoding.EncodeToString(mac.Sum(nil))
+	} else {
+		signature = hex.EncodeToString(mac.Sum(nil))
+	}
+
+	header := h.Header
+	if header == "" {
+		header = defaultHMACHeader
+	}
+	req.Header.Set(header, h.Prefix+signature)
+
+	return nil
+}
+
+// Writes the request body to the hash, leaving the body to be sent
+func hashRequestBody(req *http.Request, h hash.Hash) error {
+	switch {
+	case req.Body == nil || req.Body == http.NoBody:
+		return nil
+	case req.GetBody != nil:
+		body, err := req.GetBody()
+		if err != nil {
+			return fmt.Errorf("error reading request body to sign: %w", err)
+		}
+		defer func() {
+			_ = body.Close()
+		}()
+		if _, err := io.Copy(h, body); err != nil {
+			return fmt.Errorf("error reading request body to sign: %w", err)
+		}
+		return nil
+	}
+
+	// A body file is read, then rewound to be streamed
+	f, ok := req.Body.(*os.File)
+	if !ok {
+		return fmt.Errorf("%w: request body cannot be read", ErrInvalidSigningPolicy)
+	}
+	if _, err := io.Copy(h, f); err != nil {
+		return fmt.Errorf("error reading request body to sign: %w", err)
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("error rewinding request body: %w", err)
+	}
+	return nil
+}
+
+// The AWS config is loaded once with the SDK's default chain, so credentials
+// can come from the environment, shared files, SSO, web identity, ECS or EC2
+// instance roles. The SDK caches the credentials and refreshes them before
+// they expire.
+var loadAWSConfig = sync.OnceValues(func() (aws.Config, error) {
+	return config.LoadDefaultConfig(context.Background())
+})
+
+func (s *SigV4Signing) sign(req *http.Request, now time.Time) error {
+	cfg, err := loadAWSConfig()
+	if err != nil {
+		return fmt.Errorf("error loading aws config: %w", err)
+	}
+	if cfg.Credentials == nil {
+		return ErrNoAWSCredentials
+	}
+	creds, err := cfg.Credentials.Retrieve(req.Context())
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrNoAWSCredentials, err)
+	}
+
+	payload := sha256.New()
+	if err := hashRequestBody(req, payload); err != nil {
+		return err
+	}
+	payloadHash := hex.EncodeToString(payload.Sum(nil))
+	// S3 requires the payload hash as a header too
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	// Each path segment is encoded twice, except for S3 which is encoded once
+	signer := v4.NewSigner(func(o *v4.SignerOptions) {
+		o.DisableURIPathEscaping = s.Service == "s3"
+	})
+	if err := signer.SignHTTP(req.Context(), creds, req, payloadHash, s.Service, s.Region, now); err != nil {
+		return fmt.Errorf("error signing request: %w", err)
+	}
+
+	return nil
+}
@@ -19,15 +19,24 @@ package workflow
 import (
 	"bytes"
 	"context"
+	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"maps"
+	"mime"
 	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/itchyny/gojq"
 	"github.com/serverlessworkflow/sdk-go/v3/model"
 	"go.temporal.io/sdk/activity"
 	"go.temporal.io/sdk/temporal"
@@ -35,12 +44,452 @@ import (
 )
 
 type CallHTTPResult struct {
-	Body       string         `json:"body,omitempty"`
-	BodyJSON   map[string]any `json:"bodyJSON,omitempty"`
-	Method     string         `json:"method"`
-	Status     string         `json:"status"`
-	StatusCode int            `json:"statusCode"`
-	URL        string         `json:"url"`
+	Body        string `json:"body,omitempty"`
+	BodyBase64  string `json:"bodyBase64,omitempty"`
+	BodyJSON    any    `json:"bodyJSON,omitempty"`
+	ContentType string `json:"contentType,omitempty"`
+	File        string `json:"file,omitempty"`
+	// The response headers, with any secrets redacted
+	Headers    map[string]string `json:"headers,omitempty"`
+	Location   string            `json:"location,omitempty"`
+	Method     string            `json:"method"`
+	Size       int64             `json:"size,omitempty"`
+	Status     string            `json:"status"`
+	StatusCode int               `json:"statusCode"`
+	URL        string            `json:"url"`
+
+	// Set if the call was paginated
+	Pages     int  `json:"pages,omitempty"`
+	Truncated bool `json:"truncated,omitempty"`
+
+	// Only set if HTTP debugging is enabled - this is removed from the output
+	Debug *HTTPDebugRecord `json:"debug,omitempty"`
+
+	// Only used for pagination, so not returned
+	header     http.Header
+	requestURL string
+	size       int
+}
+
+// EmptyResponse is how an empty response body, such as a 204, is returned in
+// bodyJSON
+type EmptyResponse string
+
+const (
+	// The bodyJSON is left unset, so is null
+	EmptyResponseNull EmptyResponse = "null"
+	// The bodyJSON is an empty object, so jq against it doesn't fail
+	EmptyResponseObject EmptyResponse = "object"
+)
+
+// ParseEmptyResponse validates the empty response mode. An empty string is
+// the default of null.
+func ParseEmptyResponse(s string) (EmptyResponse, error) {
+	switch e := EmptyResponse(s); e {
+	case "":
+		return EmptyResponseNull, nil
+	case EmptyResponseNull, EmptyResponseObject:
+		return e, nil
+	default:
+		return "", fmt.Errorf("%w: %s", ErrInvalidEmptyResponse, s)
+	}
+}
+
+func (e EmptyResponse) value() any {
+	if e == EmptyResponseObject {
+		return map[string]any{}
+	}
+	return nil
+}
+
+// CallHTTPExtensions are the parts of the call http task that aren't in the
+// SDK's model, so are read from the raw workflow definition
+type CallHTTPExtensions struct {
+	With struct {
+		// Stream the request body from this file rather than holding it in memory
+		BodyFile string `json:"bodyFile,omitempty"`
+		// Stream a successful response body to this file rather than returning it
+		Download string `json:"download,omitempty"`
+		// Statuses below 400 to treat as errors, eg "3xx", "304" or "301-308"
+		ErrorStatus statusRanges `json:"errorStatus,omitempty"`
+		// Follow redirects - defaults to true. If false, a 3xx is returned with
+		// its location
+		FollowRedirects *bool `json:"followRedirects,omitempty"`
+		// Expression that builds the whole headers map, eg ${ .headers }. This
+		// isn't in the SDK's model, which only allows a map, so is removed from
+		// the definition before it's parsed. It's sent to the activity under
+		// its own name, as headers is the map.
+		Headers string `json:"headersExpression,omitempty"`
+		// A method that the SDK's model doesn't allow, eg PROPFIND. The model's
+		// method is replaced with GET before the definition is parsed, and this
+		// is sent instead.
+		Method string `json:"customMethod,omitempty"`
+		// Follow the pages of a paginated response, concatenating the items
+		Paginate *CallHTTPPaginate `json:"paginate,omitempty"`
+		// Path segments appended to the endpoint
+		Path []string `json:"path,omitempty"`
+		// JSON schema a successful response body must match
+		ResponseSchema json.RawMessage `json:"responseSchema,omitempty"`
+		// jq condition on a successful response that retries the call, eg for
+		// a 200 with an error payload
+		RetryIf string `json:"retryIf,omitempty"`
+		// Name of the signing policy to sign the request with
+		Sign string `json:"sign,omitempty"`
+	} `json:"with"`
+
+	// The signing policy named in sign, set when the workflow is loaded
+	SigningPolicy *SigningPolicy `json:"signingPolicy,omitempty"`
+}
+
+// Status ranges can be written as numbers or strings, eg 304 or "3xx"
+type statusRanges []string
+
+func (s *statusRanges) UnmarshalJSON(data []byte) error {
+	var values []any
+	if err := json.Unmarshal(data, &values); err != nil {
+		return err
+	}
+
+	*s = make(statusRanges, 0, len(values))
+	for _, v := range values {
+		*s = append(*s, fmt.Sprint(v))
+	}
+	return nil
+}
+
+// Checks if the status code is in any of the ranges, eg "3xx", "304" or "301-308"
+func statusInRanges(code int, ranges []string) (bool, error) {
+	for _, r := range ranges {
+		r = strings.ToLower(strings.TrimSpace(r))
+
+		var lower, upper int
+		var err error
+		switch {
+		case len(r) == 3 && strings.HasSuffix(r, "xx"):
+			lower, err = strconv.Atoi(r[:1])
+			lower *= 100
+			upper = lower + 99
+		case strings.Contains(r, "-"):
+			from, to, _ := strings.Cut(r, "-")
+			if lower, err = strconv.Atoi(from); err == nil {
+				upper, err = strconv.Atoi(to)
+			}
+		default:
+			lower, err = strconv.Atoi(r)
+			upper = lower
+		}
+		if err != nil || lower < 100 || upper > 599 || lower > upper {
+			return false, fmt.Errorf("%w: %s", ErrInvalidStatusRange, r)
+		}
+
+		if code >= lower && code <= upper {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// The generic activity name. Each http call is also registered with its task
+// key so its executions can be told apart in the Temporal UI.
+const callHTTPActivityName = "CallHTTP"
+
+// Versions the activity name so workflows started before tasks were named
+// replay against the generic activity.
+const callHTTPActivityNameChange = "callhttp-activity-name"
+
+// CallHTTPActivityName is the activity name for an http call task
+func CallHTTPActivityName(key string) string {
+	if key == "" {
+		return callHTTPActivityName
+	}
+	return callHTTPActivityName + ":" + key
+}
+
+// Finds the activity names of every http call in the workflow document
+func findCallHTTPActivityNames(doc any) ([]string, error) {
+	names := make([]string, 0)
+	err := walkTaskDefinitions(doc, func(key string, def any) error {
+		if d, ok := def.(map[string]any); ok && d["call"] == "http" {
+			if name := CallHTTPActivityName(key); !slices.Contains(names, name) {
+				names = append(names, name)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	slices.Sort(names)
+
+	return names, nil
+}
+
+// Find all the call http tasks with extensions in the raw workflow definition,
+// keyed by the task key
+func findCallHTTPExtensions(doc any, jqDefs []*gojq.FuncDef) (map[string]*CallHTTPExtensions, error) {
+	found := make(map[string]*CallHTTPExtensions)
+	err := walkTaskDefinitions(doc, func(key string, def any) error {
+		ext, err := getCallHTTPExtensions(def, jqDefs)
+		if err != nil {
+			return fmt.Errorf("%w: %s", err, key)
+		}
+		if ext == nil {
+			return nil
+		}
+		if _, exists := found[key]; exists {
+			return fmt.Errorf("%w: http calls using bodyFile, download, errorStatus, followRedirects, headers expressions, custom methods, paginate, path, responseSchema, retryIf or sign must have unique keys: %s", ErrDuplicateKey, key)
+		}
+		found[key] = ext
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return found, nil
+}
+
+func getCallHTTPExtensions(def any, jqDefs []*gojq.FuncDef) (*CallHTTPExtensions, error) {
+	d, ok := def.(map[string]any)
+	if !ok || d["call"] != "http" {
+		return nil, nil
+	}
+
+	b, err := json.Marshal(d)
+	if err != nil {
+		return nil, fmt.Errorf("error converting call http task to json: %w", err)
+	}
+	var ext CallHTTPExtensions
+	if err := json.Unmarshal(b, &ext); err != nil {
+		return nil, fmt.Errorf("error parsing call http task: %w", err)
+	}
+	if with, ok := d["with"].(map[string]any); ok {
+		if headers, ok := with["headers"].(string); ok {
+			ext.With.Headers = headers
+		}
+		if method, ok := with["method"].(string); ok && !isModelHTTPMethod(method) {
+			ext.With.Method = method
+		}
+	}
+
+	if ext.With.BodyFile == "" &&
+		ext.With.Download == "" &&
+		len(ext.With.ErrorStatus) == 0 &&
+		ext.With.FollowRedirects == nil &&
+		ext.With.Headers == "" &&
+		ext.With.Method == "" &&
+		ext.With.Paginate == nil &&
+		len(ext.With.Path) == 0 &&
+		len(ext.With.ResponseSchema) == 0 &&
+		ext.With.RetryIf == "" &&
+		ext.With.Sign == "" {
+		return nil, nil
+	}
+
+	// Compile the schema now so a broken one fails when the workflow is loaded
+	if len(ext.With.ResponseSchema) > 0 {
+		if _, err := compileJSONSchemaCached(ext.With.ResponseSchema); err != nil {
+			return nil, err
+		}
+	}
+
+	if ext.With.Headers != "" {
+		if !isJQExpression(ext.With.Headers) {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidHeaders, ext.With.Headers)
+		}
+		if _, err := parseJQ(model.SanitizeExpr(ext.With.Headers), "with.headers", jqDefs...); err != nil {
+			return nil, err
+		}
+	}
+
+	if ext.With.Paginate != nil {
+		if err := ext.With.Paginate.validate(ext.With.Download != "", jqDefs); err != nil {
+			return nil, err
+		}
+	}
+
+	if ext.With.RetryIf != "" {
+		if _, err := parseJQ(model.SanitizeExpr(ext.With.RetryIf), "with.retryIf", jqDefs...); err != nil {
+			return nil, err
+		}
+	}
+
+	// Check the ranges are valid now rather than when the call is made
+	if _, err := statusInRanges(0, ext.With.ErrorStatus); err != nil {
+		return nil, err
+	}
+
+	if with, ok := d["with"].(map[string]any); ok && ext.With.BodyFile != "" && with["body"] != nil {
+		return nil, ErrCallHTTPBodyAndBodyFile
+	}
+
+	return &ext, nil
+}
+
+// The methods the SDK's model allows
+var modelHTTPMethods = []string{
+	http.MethodDelete,
+	http.MethodGet,
+	http.MethodPatch,
+	http.MethodPost,
+	http.MethodPut,
+}
+
+func isModelHTTPMethod(method string) bool {
+	return slices.Contains(modelHTTPMethods, strings.ToUpper(method))
+}
+
+// Removes what the SDK's parser doesn't accept from the raw workflow
+// definition. Headers expressions are removed, as the model only allows a map,
+// and custom methods are replaced with GET. These are read into the extensions
+// first.
+func stripCallHTTPExtensions(doc any) error {
+	return walkTaskDefinitions(doc, func(_ string, def any) error {
+		d, ok := def.(map[string]any)
+		if !ok || d["call"] != "http" {
+			return nil
+		}
+		if with, ok := d["with"].(map[string]any); ok {
+			if _, ok := with["headers"].(string); ok {
+				delete(with, "headers")
+			}
+			if method, ok := with["method"].(string); ok && !isModelHTTPMethod(method) {
+				with["method"] = http.MethodGet
+			}
+		}
+		return nil
+	})
+}
+
+// Appends the path segments to the endpoint. Each segment is escaped, so can
+// contain any characters, and the slashes between them are handled so there
+// are no doubles.
+func joinEndpoint(endpoint string, segments []string) (string, error) {
+	if len(segments) == 0 {
+		return endpoint, nil
+	}
+
+	escaped := make([]string, 0, len(segments))
+	for _, s := range segments {
+		escaped = append(escaped, url.PathEscape(s))
+	}
+
+	u, err := url.JoinPath(endpoint, escaped...)
+	if err != nil {
+		return "", fmt.Errorf("error joining endpoint path: %w", err)
+	}
+
+	return u, nil
+}
+
+// How often the call http activity heartbeats while the call is in flight
+const callHTTPHeartbeatInterval = 5 * time.Second
+
+// Heartbeats until stopped. Temporal only tells an activity it's been
+// cancelled in the heartbeat response, so this lets a cancelled workflow
+// cancel the in-flight HTTP call.
+func heartbeatUntilStopped(ctx context.Context, interval time.Duration) (stop func()) {
+	done := make(chan struct{})
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-done:
+				return
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				activity.RecordHeartbeat(ctx)
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+	}
+}
+
+// The HTTP output format that returns the body as base64
+const callHTTPOutputRaw = "raw"
+
+// Non-text content types, such as images and PDFs, are returned as base64 to
+// preserve the bytes. A missing content type is treated as text.
+func isBinaryContentType(contentType string) bool {
+	if contentType == "" {
+		return false
+	}
+
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+
+	if strings.HasPrefix(mediaType, "text/") ||
+		strings.HasSuffix(mediaType, "+json") ||
+		strings.HasSuffix(mediaType, "+xml") ||
+		strings.HasSuffix(mediaType, "+yaml") {
+		return false
+	}
+
+	switch mediaType {
+	case "application/json",
+		"application/javascript",
+		"application/x-www-form-urlencoded",
+		"application/x-yaml",
+		"application/xml",
+		"application/yaml":
+		return false
+	}
+
+	return true
+}
+
+// Streams the response body to a file
+func downloadBody(body io.Reader, file string) (int64, error) {
+	f, err := os.Create(filepath.Clean(file))
+	if err != nil {
+		return 0, fmt.Errorf("error creating download file: %w", err)
+	}
+
+	size, err := io.Copy(f, body)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return 0, fmt.Errorf("error writing download file: %w", err)
+	}
+
+	return size, nil
+}
+
+// Headers with any of these in the name have their values redacted in logs
+// and results
+var sensitiveHeaders = []string{"auth", "cookie", "key", "password", "secret", "session", "token"}
+
+const redactedValue = "***"
+
+// Redacts any header values that may contain secrets
+func redactHeaders(headers http.Header) map[string]string {
+	redacted := make(map[string]string, len(headers))
+	for k, v := range headers {
+		value := strings.Join(v, ", ")
+
+		name := strings.ToLower(k)
+		for _, s := range sensitiveHeaders {
+			if strings.Contains(name, s) {
+				value = redactedValue
+				break
+			}
+		}
+
+		redacted[k] = value
+	}
+
+	return redacted
 }
 
 func parseCallBody(input json.RawMessage, data *Variables) ([]byte, error) {
@@ -62,7 +511,7 @@ func parseCallBody(input json.RawMessage, data *Variables) ([]byte, error) {
 	if err != nil {
 		return nil, fmt.Errorf("error unmarshalling body: %w", err)
 	}
-	body, err := ParseVariables(string(d), data)
+	body, err := parseCallField(string(d), "with.body", data)
 	if err != nil {
 		return nil, fmt.Errorf("error interpolating body: %w", err)
 	}
@@ -70,46 +519,297 @@ func parseCallBody(input json.RawMessage, data *Variables) ([]byte, error) {
 	return []byte(body), nil
 }
 
-func (a *activities) CallHTTP(ctx context.Context, callHttp *model.CallHTTP, vars *Variables) (*CallHTTPResult, error) {
+// Any HTTP token is a valid method and is sent as-is, so non-standard methods
+// such as PROPFIND, MKCOL or PURGE can be used
+var httpMethodToken = regexp.MustCompile("^[!#$%&'*+\\-.^_`|~0-9A-Za-z]+$")
+
+// Interpolates a field in the call. A broken expression won't fix itself on
+// retry so is treated as non-retryable
+func parseCallField(input, field string, data *Variables) (string, error) {
+	value, err := ParseVariables(input, data)
+	if err != nil {
+		err = WithExpressionContext(err, "", field)
+		return "", temporal.NewNonRetryableApplicationError(err.Error(), string(ExpressionErr), err)
+	}
+
+	return value, nil
+}
+
+// Interpolates a query parameter's value. Arrays are sent as the key repeated
+// for each item, eg tags=a&tags=b, and objects are sent JSON encoded.
+func parseCallQuery(key string, input any, data *Variables) ([]string, error) {
+	field := "with.query." + key
+
+	items, ok := input.([]any)
+	if !ok {
+		items = []any{input}
+	}
+
+	values := make([]string, 0, len(items))
+	for i, item := range items {
+		itemField := field
+		if ok {
+			itemField = fieldPath(field, strconv.Itoa(i))
+		}
+
+		v, err := interpolate(item, data, itemField)
+		if err != nil {
+			return nil, temporal.NewNonRetryableApplicationError(err.Error(), string(ExpressionErr), err)
+		}
+
+		switch value := v.(type) {
+		case string:
+			values = append(values, value)
+		case map[string]any, []any:
+			b, err := json.Marshal(value)
+			if err != nil {
+				return nil, fmt.Errorf("error encoding query %s: %w", itemField, err)
+			}
+			values = append(values, string(b))
+		case nil:
+			values = append(values, "")
+		default:
+			values = append(values, fmt.Sprint(value))
+		}
+	}
+
+	return values, nil
+}
+
+// Evaluates the headers expression, which must give an object of strings.
+// Numbers and booleans are sent as text and null values are skipped.
+func evaluateCallHeaders(expression string, data *Variables) (map[string]string, error) {
+	v, err := EvaluateJQ(expression, "with.headers", data)
+	if err != nil {
+		return nil, temporal.NewNonRetryableApplicationError(err.Error(), string(ExpressionErr), err)
+	}
+
+	obj, ok := v.(map[string]any)
+	if !ok {
+		err := fmt.Errorf("%w: got %T", ErrInvalidHeaders, v)
+		return nil, temporal.NewNonRetryableApplicationError(err.Error(), string(ExpressionErr), err)
+	}
+
+	headers := make(map[string]string, len(obj))
+	for k, value := range obj {
+		switch value := value.(type) {
+		case nil:
+		case map[string]any, []any:
+			err := fmt.Errorf("%w: %s must be a string", ErrInvalidHeaders, k)
+			return nil, temporal.NewNonRetryableApplicationError(err.Error(), string(ExpressionErr), err)
+		default:
+			headers[k] = fmt.Sprint(value)
+		}
+	}
+
+	return headers, nil
+}
+
+func (a *activities) CallHTTP(ctx context.Context, callHttp *model.CallHTTP, ext *CallHTTPExtensions, vars *Variables) (*CallHTTPResult, error) {
+	result, err := a.callHTTP(ctx, callHttp, ext, vars, "")
+	if err != nil || ext == nil || ext.With.Paginate == nil {
+		return result, err
+	}
+
+	return a.paginate(ctx, callHttp, ext, vars, result)
+}
+
+// Makes a single http call. If the page URL is set, it's requested instead of
+// the endpoint, path and query, such as to get the next page.
+func (a *activities) callHTTP(
+	ctx context.Context,
+	callHttp *model.CallHTTP,
+	ext *CallHTTPExtensions,
+	vars *Variables,
+	pageURL string,
+) (*CallHTTPResult, error) {
 	logger := activity.GetLogger(ctx)
 	logger.Debug("Running call HTTP activity")
 
-	vars = vars.Clone()
-	vars.AddData(GetActivityVars(ctx))
+	vars = a.withActivityVars(ctx, vars)
 
 	body, err := parseCallBody(callHttp.With.Body, vars)
 	if err != nil {
 		return nil, err
 	}
 
-	method := strings.ToUpper(MustParseVariables(callHttp.With.Method, vars))
-	url := MustParseVariables(callHttp.With.Endpoint.String(), vars)
+	method := callHttp.With.Method
+	if ext != nil && ext.With.Method != "" {
+		method = ext.With.Method
+	}
+	method, err = parseCallField(method, "with.method", vars)
+	if err != nil {
+		return nil, err
+	}
+	method = strings.ToUpper(method)
+	if !httpMethodToken.MatchString(method) {
+		err := fmt.Errorf("%w: %q", ErrInvalidHTTPMethod, method)
+		return nil, temporal.NewNonRetryableApplicationError(err.Error(), string(CallHTTPErr), err)
+	}
+
+	url, err := parseCallField(callHttp.With.Endpoint.String(), "with.endpoint", vars)
+	if err != nil {
+		return nil, err
+	}
+
+	if pageURL != "" {
+		url = pageURL
+	} else if ext != nil && len(ext.With.Path) > 0 {
+		segments := make([]string, 0, len(ext.With.Path))
+		for i, s := range ext.With.Path {
+			segment, err := parseCallField(s, fmt.Sprintf("with.path[%d]", i), vars)
+			if err != nil {
+				return nil, err
+			}
+			segments = append(segments, segment)
+		}
+
+		url, err = joinEndpoint(url, segments)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var download string
+	if ext != nil && ext.With.Download != "" {
+		download, err = parseCallField(ext.With.Download, "with.download", vars)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// The request body is either the interpolated body or streamed from a file
+	var reqBody io.Reader = bytes.NewBuffer(body)
+	var contentLength int64 = -1
+	if ext != nil && ext.With.BodyFile != "" {
+		bodyFile, err := parseCallField(ext.With.BodyFile, "with.bodyFile", vars)
+		if err != nil {
+			return nil, err
+		}
+
+		f, err := os.Open(filepath.Clean(bodyFile))
+		if err != nil {
+			logger.Error("Error opening body file", "file", bodyFile, "error", err)
+			return nil, fmt.Errorf("error opening body file: %w", err)
+		}
+		defer func() {
+			if err := f.Close(); err != nil {
+				logger.Error("Error closing body file", "error", err)
+			}
+		}()
+
+		if stat, err := f.Stat(); err == nil {
+			contentLength = stat.Size()
+		}
+
+		reqBody = f
+		// Only used for logging
+		body = []byte(fmt.Sprintf("<streamed from %s>", bodyFile))
+	}
 
 	logger.Debug("Making HTTP call", "method", method, "url", url)
-	req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewBuffer(body))
+	req, err := http.NewRequestWithContext(ctx, method, url, reqBody)
 	if err != nil {
 		logger.Error("Error making HTTP request", "method", method, "url", url, "error", err)
 		return nil, fmt.Errorf("error making http request: %w", err)
 	}
+	if contentLength >= 0 {
+		// A file isn't sized automatically, so would be sent chunked
+		req.ContentLength = contentLength
+	}
 
 	for k, v := range callHttp.With.Headers {
-		req.Header.Add(k, MustParseVariables(v, vars))
+		header, err := parseCallField(v, "with.headers."+k, vars)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Add(k, header)
+	}
+	if ext != nil && ext.With.Headers != "" {
+		headers, err := evaluateCallHeaders(ext.With.Headers, vars)
+		if err != nil {
+			return nil, err
+		}
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+	}
+
+	// A page URL already has its query
+	if pageURL == "" {
+		q := req.URL.Query()
+		for k, v := range callHttp.With.Query {
+			values, err := parseCallQuery(k, v, vars)
+			if err != nil {
+				return nil, err
+			}
+			for _, value := range values {
+				q.Add(k, value)
+			}
+		}
+		req.URL.RawQuery = q.Encode()
+	}
+
+	if ext != nil && ext.SigningPolicy != nil {
+		// Signed last so the signature covers exactly what's sent
+		if err := ext.SigningPolicy.sign(req, vars, time.Now()); err != nil {
+			logger.Error("Error signing HTTP request", "sign", ext.With.Sign, "error", err)
+			return nil, fmt.Errorf("error signing http request: %w", err)
+		}
 	}
 
-	q := req.URL.Query()
-	for k, v := range callHttp.With.Query {
-		q.Add(k, MustParseVariables(v.(string), vars))
+	if a.httpDryRun {
+		// Log what would be sent, but don't send it
+		logger.Warn("HTTP dry run enabled - request not sent",
+			"method", method,
+			"url", req.URL.String(),
+			"headers", redactHeaders(req.Header),
+			"body", string(body),
+		)
+
+		return &CallHTTPResult{
+			Method:     method,
+			Status:     fmt.Sprintf("%d %s", http.StatusOK, http.StatusText(http.StatusOK)),
+			StatusCode: http.StatusOK,
+			URL:        req.URL.String(),
+		}, nil
 	}
-	req.URL.RawQuery = q.Encode()
+
+	var debug *HTTPDebugRecord
+	if a.httpDebug || a.httpDebugFile != "" {
+		debug = newHTTPDebugRecord(req, body)
+		defer func() {
+			if a.httpDebugFile == "" {
+				return
+			}
+			if err := writeHTTPDebugFile(a.httpDebugFile, debug); err != nil {
+				logger.Error("Error writing HTTP debug file", "error", err)
+			}
+		}()
+	}
+
+	stopHeartbeat := heartbeatUntilStopped(ctx, callHTTPHeartbeatInterval)
+	defer stopHeartbeat()
 
 	// @todo(sje): configure the timeout
 	client := http.Client{
 		Timeout: 30 * time.Second,
 	}
+	if ext != nil && ext.With.FollowRedirects != nil && !*ext.With.FollowRedirects {
+		// Return the redirect rather than following it
+		client.CheckRedirect = func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		}
+	}
 
+	start := time.Now()
 	resp, err := client.Do(req)
+	recordHTTPCallLatency(ctx, req.URL.Host, resp, time.Since(start))
 	if err != nil {
 		logger.Error("Error making HTTP call", "method", method, "url", url, "error", err)
+		if debug != nil {
+			debug.Error = err.Error()
+		}
 		return nil, fmt.Errorf("error making http call: %w", err)
 	}
 	defer func() {
@@ -119,19 +819,108 @@ func (a *activities) CallHTTP(ctx context.Context, callHttp *model.CallHTTP, var
 		}
 	}()
 
-	bodyRes, err := io.ReadAll(resp.Body)
+	contentType := resp.Header.Get("Content-Type")
+
+	respBody, err := decodedBody(resp)
+	if err != nil {
+		logger.Error("Error decoding HTTP body", "method", method, "url", url, "error", err)
+		if errors.Is(err, ErrUnsupportedContentEncoding) {
+			return nil, temporal.NewNonRetryableApplicationError(err.Error(), string(CallHTTPErr), err)
+		}
+		return nil, err
+	}
+
+	var errorStatus bool
+	if ext != nil && resp.StatusCode < 400 {
+		if errorStatus, err = statusInRanges(resp.StatusCode, ext.With.ErrorStatus); err != nil {
+			return nil, temporal.NewNonRetryableApplicationError(err.Error(), string(CallHTTPErr), err)
+		}
+	}
+
+	// Only successful responses are downloaded - a redirect that's not
+	// followed is returned with its location
+	if download != "" && resp.StatusCode >= 200 && resp.StatusCode < 300 && !errorStatus {
+		// Stream the body to the file without holding it in memory
+		logger.Debug("Downloading HTTP body", "method", method, "url", url, "file", download)
+		size, err := downloadBody(respBody, download)
+		if err != nil {
+			logger.Error("Error downloading HTTP body", "method", method, "url", url, "file", download, "error", err)
+			return nil, err
+		}
+
+		var debugResult *HTTPDebugRecord
+		if debug != nil {
+			debug.setResponse(resp, []byte(fmt.Sprintf("<%d bytes downloaded to %s>", size, download)))
+			if a.httpDebug {
+				debugResult = debug
+			}
+		}
+
+		return &CallHTTPResult{
+			ContentType: contentType,
+			File:        download,
+			Headers:     redactHeaders(resp.Header),
+			Method:      method,
+			Size:        size,
+			Status:      resp.Status,
+			StatusCode:  resp.StatusCode,
+			URL:         url,
+			Debug:       debugResult,
+		}, nil
+	}
+
+	bodyRes, err := io.ReadAll(respBody)
 	if err != nil {
 		logger.Error("Error reading HTTP body", "method", method, "url", url, "error", err)
 		return nil, fmt.Errorf("error reading http body: %w", err)
 	}
 
-	// Try converting the body as JSON, returning as string if not possible
-	var bodyJSON map[string]any
-	var bodyStr string
-	if err := json.Unmarshal(bodyRes, &bodyJSON); err != nil {
-		// Log error
-		logger.Debug("Error converting body to JSON", "error", err)
-		bodyStr = string(bodyRes)
+	// Try converting the body as JSON, returning as string if not possible.
+	// Binary bodies are returned as base64 so the bytes aren't mangled.
+	var bodyJSON any
+	var bodyStr, bodyBase64 string
+	if callHttp.With.Output == callHTTPOutputRaw || isBinaryContentType(contentType) {
+		bodyBase64 = base64.StdEncoding.EncodeToString(bodyRes)
+	} else if len(bytes.TrimSpace(bodyRes)) == 0 {
+		// No content, eg a 204
+		bodyJSON = a.emptyResponse.value()
+	} else {
+		var obj map[string]any
+		if err := decodeJSON(bodyRes, &obj); err != nil {
+			// Log error
+			logger.Debug("Error converting body to JSON", "error", err)
+			bodyStr = string(bodyRes)
+		} else {
+			bodyJSON = obj
+		}
+	}
+
+	// Only returned to the workflow if it's recording the calls
+	var debugResult *HTTPDebugRecord
+	if debug != nil {
+		if bodyBase64 != "" {
+			debug.setResponse(resp, []byte(bodyBase64))
+		} else {
+			debug.setResponse(resp, bodyRes)
+		}
+		if a.httpDebug {
+			debugResult = debug
+		}
+	}
+
+	if errorStatus {
+		// Configured by the author so won't change on retry
+		logger.Error("CallHTTP returned an error status", "status", resp.StatusCode)
+
+		details := a.httpErrorDetails(resp.StatusCode, bodyRes, bodyStr, bodyBase64, bodyJSON, debugResult)
+		details["location"] = resp.Header.Get("Location")
+
+		return nil, temporal.NewNonRetryableApplicationError(
+			fmt.Sprintf("CallHTTP returned %d error status", resp.StatusCode),
+			string(CallHTTPErr),
+			errors.New(resp.Status),
+			details,
+		)
 	}
 
 	if resp.StatusCode >= 400 && resp.StatusCode < 500 {
@@ -142,11 +931,7 @@ func (a *activities) CallHTTP(ctx context.Context, callHttp *model.CallHTTP, var
 			"CallHTTP returned 4xx error",
 			string(CallHTTPErr),
 			errors.New(resp.Status),
-			HTTPData{
-				"status": resp.StatusCode,
-				"body":   bodyStr,
-				"json":   bodyJSON,
-			},
+			a.httpErrorDetails(resp.StatusCode, bodyRes, bodyStr, bodyBase64, bodyJSON, debugResult),
 		)
 	}
 
@@ -154,35 +939,118 @@ func (a *activities) CallHTTP(ctx context.Context, callHttp *model.CallHTTP, var
 		// Error on their side - treat as retryable error as we can't fix it
 		logger.Error("CallHTTP returned 5xx error")
 
-		return nil, temporal.NewApplicationError("CallHTTP returned 5xx error", string(CallHTTPErr), errors.New(resp.Status), HTTPData{
-			"status": resp.StatusCode,
-			"body":   bodyStr,
-			"json":   bodyJSON,
+		return nil, temporal.NewApplicationError("CallHTTP returned 5xx error", string(CallHTTPErr), errors.New(resp.Status),
+			a.httpErrorDetails(resp.StatusCode, bodyRes, bodyStr, bodyBase64, bodyJSON, debugResult))
+	}
+
+	if ext != nil && ext.With.RetryIf != "" && resp.StatusCode < 400 {
+		// The response is the input, rather than the variables
+		retry, err := evaluateCondition(ext.With.RetryIf, "with.retryIf", &Variables{
+			Data: HTTPData{
+				"body":        bodyStr,
+				"bodyJSON":    bodyJSON,
+				"contentType": contentType,
+				"statusCode":  resp.StatusCode,
+			},
+			jqDefs: a.jqDefs,
 		})
+		if err != nil {
+			logger.Error("Error checking retryIf", "error", err)
+			return nil, err
+		}
+		if retry {
+			// The status doesn't reflect the failure, so let the call be retried
+			logger.Warn("CallHTTP response matched retryIf", "status", resp.StatusCode)
+
+			return nil, temporal.NewApplicationError(
+				"CallHTTP response matched retryIf",
+				string(CallHTTPErr),
+				errors.New(resp.Status),
+				a.httpErrorDetails(resp.StatusCode, bodyRes, bodyStr, bodyBase64, bodyJSON, debugResult),
+			)
+		}
+	}
+
+	if ext != nil && len(ext.With.ResponseSchema) > 0 && resp.StatusCode < 300 {
+		if err := checkResponseSchema(ext.With.ResponseSchema, bodyRes); err != nil {
+			// The upstream's contract has changed - this won't fix itself on retry
+			logger.Error("CallHTTP response doesn't match the schema", "error", err)
+
+			return nil, temporal.NewNonRetryableApplicationError(
+				err.Error(),
+				string(ResponseSchemaErr),
+				err,
+				HTTPData{
+					"status": resp.StatusCode,
+					"body":   bodyStr,
+					"json":   bodyJSON,
+				},
+			)
+		}
 	}
 
 	return &CallHTTPResult{
-		Body:       bodyStr,
-		BodyJSON:   bodyJSON,
-		Method:     method,
-		Status:     resp.Status,
-		StatusCode: resp.StatusCode,
-		URL:        url,
+		Body:        bodyStr,
+		BodyBase64:  bodyBase64,
+		BodyJSON:    bodyJSON,
+		ContentType: contentType,
+		Headers:     redactHeaders(resp.Header),
+		Location:    resp.Header.Get("Location"),
+		Method:      method,
+		Status:      resp.Status,
+		StatusCode:  resp.StatusCode,
+		URL:         url,
+		Debug:       debugResult,
+
+		header:     resp.Header,
+		requestURL: req.URL.String(),
+		size:       len(bodyRes),
 	}, err
 }
 
-func httpTaskImpl(task *model.CallHTTP, key string) TemporalWorkflowFunc {
+func httpTaskImpl(
+	task *model.CallHTTP,
+	key string,
+	ext *CallHTTPExtensions,
+	debugSize int,
+	taskQueues HTTPTaskQueues,
+) TemporalWorkflowFunc {
 	var a *activities
 
 	return func(ctx workflow.Context, data *Variables, output map[string]OutputType) error {
 		logger := workflow.GetLogger(ctx)
 		logger.Debug("Calling HTTP endpoint")
 
+		if len(taskQueues) > 0 {
+			// Route the call to the task queue for its host
+			endpoint, err := parseCallField(task.With.Endpoint.String(), "with.endpoint", data)
+			if err != nil {
+				return err
+			}
+			queue, err := taskQueues.taskQueue(endpoint)
+			if err != nil {
+				return temporal.NewNonRetryableApplicationError(err.Error(), string(CallHTTPErr), err)
+			}
+			if queue != "" {
+				logger.Debug("Routing HTTP call to task queue", "taskQueue", queue)
+				ctx = workflow.WithTaskQueue(ctx, queue)
+			}
+		}
+
+		var activityFn any = a.CallHTTP
+		if workflow.GetVersion(ctx, callHTTPActivityNameChange, workflow.DefaultVersion, 1) == 1 {
+			activityFn = CallHTTPActivityName(key)
+		}
+
 		var result CallHTTPResult
-		if err := workflow.ExecuteActivity(ctx, a.CallHTTP, task, data).Get(ctx, &result); err != nil {
+		if err := workflow.ExecuteActivity(ctx, activityFn, task, ext, data).Get(ctx, &result); err != nil {
+			data.recordHTTPDebug(httpDebugFromError(err), debugSize)
 			return fmt.Errorf("error calling http task: %w", err)
 		}
 
+		data.recordHTTPDebug(result.Debug, debugSize)
+		result.Debug = nil
+
 		maps.Copy(output, map[string]OutputType{
 			key: {
 				Type: CallHTTPResultType,
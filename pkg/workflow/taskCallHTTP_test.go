@@ -0,0 +1,641 @@
+/*
+ * Copyright 2025 Simon Emms <simon@simonemms.com>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package workflow
+
+import (
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	"go.temporal.io/sdk/temporal"
+)
+
+// Runs a workflow with a single http call to the handler, returning the
+// call's result. The with is indented under the call, with the endpoint set to
+// the server.
+func runTestHTTPCall(t *testing.T, with string, handler http.HandlerFunc, opts ...Option) (map[string]any, error) {
+	t.Helper()
+
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	w := loadTestWorkflow(t, fmt.Sprintf(`
+document:
+  dsl: 1.0.0
+  namespace: test
+  name: http
+  version: 0.0.1
+do:
+  - call:
+      call: http
+      with:
+        endpoint: %s
+%s
+`, server.URL, with), opts...)
+
+	output, err := runTestWorkflow(t, w, buildTestWorkflow(t, w, "http"), HTTPData{})
+	if err != nil {
+		return nil, err
+	}
+
+	call, _ := output["call"].(map[string]any)
+	result, ok := call["data"].(map[string]any)
+	if !ok {
+		t.Fatalf("http call has no result: %#v", output)
+	}
+
+	return result, nil
+}
+
+func TestCallHTTPEmptyResponse(t *testing.T) {
+	tests := []struct {
+		name          string
+		statusCode    int
+		emptyResponse EmptyResponse
+		expected      any
+	}{
+		{
+			name:       "204 is null by default",
+			statusCode: http.StatusNoContent,
+		},
+		{
+			name:       "empty 200 is null by default",
+			statusCode: http.StatusOK,
+		},
+		{
+			name:          "204 as an object",
+			statusCode:    http.StatusNoContent,
+			emptyResponse: EmptyResponseObject,
+			expected:      map[string]any{},
+		},
+		{
+			name:          "empty 200 as an object",
+			statusCode:    http.StatusOK,
+			emptyResponse: EmptyResponseObject,
+			expected:      map[string]any{},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result, err := runTestHTTPCall(t, "        method: get", func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(test.statusCode)
+			}, WithEmptyResponse(test.emptyResponse))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if result["statusCode"] != float64(test.statusCode) {
+				t.Errorf("expected status %d, got %v", test.statusCode, result["statusCode"])
+			}
+			if _, ok := result["body"]; ok {
+				t.Errorf("expected no body, got %#v", result["body"])
+			}
+			if !reflect.DeepEqual(result["bodyJSON"], test.expected) {
+				t.Errorf("expected bodyJSON %#v, got %#v", test.expected, result["bodyJSON"])
+			}
+		})
+	}
+}
+
+func TestCallHTTPQueryValues(t *testing.T) {
+	var query url.Values
+	_, err := runTestHTTPCall(t, `        method: get
+        query:
+          tags:
+            - a
+            # Each item is interpolated
+            - '{{ "b" }}'
+          filter:
+            status: open
+          page: 2`, func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.Query()
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := url.Values{
+		"tags":   {"a", "b"},
+		"filter": {`{"status":"open"}`},
+		"page":   {"2"},
+	}
+	if !reflect.DeepEqual(query, expected) {
+		t.Errorf("expected %v, got %v", expected, query)
+	}
+}
+
+func TestCallHTTPEncodedResponse(t *testing.T) {
+	tests := []struct {
+		name     string
+		encoding string
+		writer   func(w io.Writer) io.WriteCloser
+	}{
+		{
+			name:     "brotli",
+			encoding: "br",
+			writer: func(w io.Writer) io.WriteCloser {
+				return brotli.NewWriter(w)
+			},
+		},
+		{
+			name:     "gzip",
+			encoding: "gzip",
+			writer: func(w io.Writer) io.WriteCloser {
+				return gzip.NewWriter(w)
+			},
+		},
+		{
+			name:     "deflate",
+			encoding: "deflate",
+			writer: func(w io.Writer) io.WriteCloser {
+				return zlib.NewWriter(w)
+			},
+		},
+		{
+			// Some servers send deflate without the zlib wrapper
+			name:     "raw deflate",
+			encoding: "deflate",
+			writer: func(w io.Writer) io.WriteCloser {
+				fw, _ := flate.NewWriter(w, flate.DefaultCompression)
+				return fw
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// Setting the Accept-Encoding stops the transport decoding the body
+			result, err := runTestHTTPCall(t, `        method: get
+        headers:
+          accept-encoding: `+test.encoding, func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				w.Header().Set("Content-Encoding", test.encoding)
+
+				enc := test.writer(w)
+				_, _ = enc.Write([]byte(`{"name": "alice"}`))
+				_ = enc.Close()
+			})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			expected := map[string]any{"name": "alice"}
+			if !reflect.DeepEqual(result["bodyJSON"], expected) {
+				t.Errorf("expected bodyJSON %#v, got %#v", expected, result["bodyJSON"])
+			}
+		})
+	}
+}
+
+func TestCallHTTPMethod(t *testing.T) {
+	tests := []struct {
+		method    string
+		expected  string
+		expectErr bool
+	}{
+		{
+			method:   "get",
+			expected: http.MethodGet,
+		},
+		{
+			method:   "PROPFIND",
+			expected: "PROPFIND",
+		},
+		{
+			method:   "mkcol",
+			expected: "MKCOL",
+		},
+		{
+			method:   "purge",
+			expected: "PURGE",
+		},
+		{
+			method:    "'not a token'",
+			expectErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.method, func(t *testing.T) {
+			var method string
+			result, err := runTestHTTPCall(t, "        method: "+test.method, func(w http.ResponseWriter, r *http.Request) {
+				method = r.Method
+			})
+			if test.expectErr {
+				var appErr *temporal.ApplicationError
+				if !errors.As(err, &appErr) || !appErr.NonRetryable() {
+					t.Fatalf("expected a non-retryable error, got %v", err)
+				}
+				if method != "" {
+					t.Errorf("expected no request, got %s", method)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if method != test.expected {
+				t.Errorf("expected %s to be sent, got %s", test.expected, method)
+			}
+			if result["method"] != test.expected {
+				t.Errorf("expected %s to be returned, got %v", test.expected, result["method"])
+			}
+		})
+	}
+}
+
+func TestCallHTTPAttempt(t *testing.T) {
+	attempts := make([]string, 0)
+	result, err := runTestHTTPCall(t, `        method: get
+        headers:
+          x-attempt: '{{ .attempt }}'
+          x-retry: '{{ if gt .attempt 1 }}true{{ else }}false{{ end }}'`, func(w http.ResponseWriter, r *http.Request) {
+		attempts = append(attempts, r.Header.Get("x-attempt")+":"+r.Header.Get("x-retry"))
+		if len(attempts) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []string{"1:false", "2:true", "3:true"}
+	if !slices.Equal(attempts, expected) {
+		t.Errorf("expected %v, got %v", expected, attempts)
+	}
+	if result["statusCode"] != float64(http.StatusOK) {
+		t.Errorf("expected the last attempt to succeed, got %v", result["statusCode"])
+	}
+}
+
+func TestCallHTTPErrorBodySize(t *testing.T) {
+	body := `{"error": "` + strings.Repeat("x", 2*DefaultHTTPErrorBodySize) + `"}`
+
+	tests := []struct {
+		name      string
+		opts      []Option
+		expected  int
+		truncated bool
+	}{
+		{
+			name:      "default",
+			expected:  DefaultHTTPErrorBodySize,
+			truncated: true,
+		},
+		{
+			name:      "configured",
+			opts:      []Option{WithHTTPErrorBodySize(100)},
+			expected:  100,
+			truncated: true,
+		},
+		{
+			name:     "unlimited",
+			opts:     []Option{WithHTTPErrorBodySize(0)},
+			expected: len(body),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := runTestHTTPCall(t, "        method: get", func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusBadRequest)
+				_, _ = w.Write([]byte(body))
+			}, test.opts...)
+
+			// The details are on the activity's error
+			var actErr *temporal.ActivityError
+			var appErr *temporal.ApplicationError
+			if !errors.As(err, &actErr) || !errors.As(actErr, &appErr) || appErr.Type() != string(CallHTTPErr) {
+				t.Fatalf("expected %s, got %v", CallHTTPErr, err)
+			}
+
+			var details map[string]any
+			if err := appErr.Details(&details); err != nil {
+				t.Fatalf("error getting error details: %v", err)
+			}
+
+			if test.truncated {
+				// A partial body can't be decoded, so is returned as a string
+				if got, _ := details["body"].(string); len(got) != test.expected {
+					t.Errorf("expected a body of %d bytes, got %d", test.expected, len(got))
+				}
+				if details["json"] != nil {
+					t.Errorf("expected no json, got %v", details["json"])
+				}
+				if details["truncated"] != true {
+					t.Errorf("expected truncated to be set, got %v", details["truncated"])
+				}
+				return
+			}
+
+			if got, _ := details["json"].(map[string]any); len(fmt.Sprint(got["error"])) != 2*DefaultHTTPErrorBodySize {
+				t.Errorf("expected the whole json body, got %d bytes", len(fmt.Sprint(got["error"])))
+			}
+			if _, ok := details["truncated"]; ok {
+				t.Errorf("expected truncated not to be set, got %v", details["truncated"])
+			}
+		})
+	}
+}
+
+func TestCallHTTPHeadersExpression(t *testing.T) {
+	var headers http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		headers = r.Header
+	}))
+	t.Cleanup(server.Close)
+
+	w := loadTestWorkflow(t, fmt.Sprintf(`
+document:
+  dsl: 1.0.0
+  namespace: test
+  name: http
+  version: 0.0.1
+use:
+  jqFunctions: |
+    def tenantHeaders: {"x-tenant": .tenant};
+do:
+  - call:
+      call: http
+      with:
+        method: get
+        endpoint: %s
+        headers: '${ tenantHeaders + {"x-count": 2, "x-skipped": null} }'
+`, server.URL))
+
+	if _, err := runTestWorkflow(t, w, buildTestWorkflow(t, w, "http"), HTTPData{"tenant": "acme"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// The expression can use the document's jq functions
+	if got := headers.Get("x-tenant"); got != "acme" {
+		t.Errorf("expected x-tenant to be acme, got %q", got)
+	}
+	if got := headers.Get("x-count"); got != "2" {
+		t.Errorf("expected x-count to be 2, got %q", got)
+	}
+	if _, ok := headers["X-Skipped"]; ok {
+		t.Errorf("expected null headers to be skipped, got %q", headers.Get("x-skipped"))
+	}
+}
+
+func TestCallHTTPResult(t *testing.T) {
+	t.Run("json", func(t *testing.T) {
+		result, err := runTestHTTPCall(t, "        method: get", func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Add("X-Ratelimit-Remaining", "10")
+			w.Header().Add("Vary", "Accept")
+			w.Header().Add("Vary", "Origin")
+			w.Header().Set("Set-Cookie", "session=secret")
+			_, _ = w.Write([]byte(`{"id": 1}`))
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if expected := map[string]any{"id": float64(1)}; !reflect.DeepEqual(result["bodyJSON"], expected) {
+			t.Errorf("expected bodyJSON %v, got %v", expected, result["bodyJSON"])
+		}
+		if _, ok := result["body"]; ok {
+			t.Errorf("expected no body, got %v", result["body"])
+		}
+
+		// Repeated headers are joined and secrets are redacted
+		headers, _ := result["headers"].(map[string]any)
+		for k, v := range map[string]string{
+			"X-Ratelimit-Remaining": "10",
+			"Vary":                  "Accept, Origin",
+			"Set-Cookie":            redactedValue,
+			"Content-Type":          "application/json",
+		} {
+			if headers[k] != v {
+				t.Errorf("expected header %s to be %q, got %v", k, v, headers[k])
+			}
+		}
+	})
+
+	t.Run("text", func(t *testing.T) {
+		result, err := runTestHTTPCall(t, "        method: get", func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/plain")
+			_, _ = w.Write([]byte("hello"))
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if result["body"] != "hello" {
+			t.Errorf("expected body hello, got %v", result["body"])
+		}
+		if _, ok := result["bodyJSON"]; ok {
+			t.Errorf("expected no bodyJSON, got %v", result["bodyJSON"])
+		}
+	})
+
+	t.Run("4xx isn't retried", func(t *testing.T) {
+		calls := 0
+		_, err := runTestHTTPCall(t, "        method: get", func(w http.ResponseWriter, r *http.Request) {
+			calls++
+			w.WriteHeader(http.StatusNotFound)
+		})
+
+		var appErr *temporal.ApplicationError
+		if !errors.As(err, &appErr) || appErr.Type() != string(CallHTTPErr) || !appErr.NonRetryable() {
+			t.Fatalf("expected a non-retryable %s, got %v", CallHTTPErr, err)
+		}
+		if calls != 1 {
+			t.Errorf("expected 1 call, got %d", calls)
+		}
+	})
+
+	t.Run("5xx is retried", func(t *testing.T) {
+		calls := 0
+		result, err := runTestHTTPCall(t, "        method: get", func(w http.ResponseWriter, r *http.Request) {
+			calls++
+			if calls == 1 {
+				w.WriteHeader(http.StatusBadGateway)
+			}
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if calls != 2 {
+			t.Errorf("expected 2 calls, got %d", calls)
+		}
+		if result["statusCode"] != float64(http.StatusOK) {
+			t.Errorf("expected the retry to succeed, got %v", result["statusCode"])
+		}
+	})
+
+	t.Run("broken expression fails the call", func(t *testing.T) {
+		calls := 0
+		_, err := runTestHTTPCall(t, `        method: get
+        headers:
+          x-broken: '{{ .missing | nope }}'`, func(w http.ResponseWriter, r *http.Request) {
+			calls++
+		})
+
+		var appErr *temporal.ApplicationError
+		if !errors.As(err, &appErr) || appErr.Type() != string(ExpressionErr) || !appErr.NonRetryable() {
+			t.Fatalf("expected a non-retryable %s, got %v", ExpressionErr, err)
+		}
+		if calls != 0 {
+			t.Errorf("expected no calls, got %d", calls)
+		}
+	})
+}
+
+func TestCallHTTPAnchoredHeaders(t *testing.T) {
+	w := loadTestWorkflow(t, `
+document:
+  dsl: 1.0.0
+  namespace: test
+  name: http
+  version: 0.0.1
+do:
+  - getUser:
+      call: http
+      with:
+        method: get
+        endpoint: https://example.com/users/1
+        headers: &headers
+          authorization: Bearer token
+          accept: application/json
+  - getOrders:
+      call: http
+      with:
+        method: get
+        endpoint: https://example.com/users/1/orders
+        headers: *headers
+  - getInvoices:
+      call: http
+      with:
+        method: get
+        endpoint: https://example.com/users/1/invoices
+        headers:
+          <<: *headers
+          accept: application/pdf
+`)
+
+	expected := map[string]map[string]string{
+		"getUser": {
+			"authorization": "Bearer token",
+			"accept":        "application/json",
+		},
+		"getOrders": {
+			"authorization": "Bearer token",
+			"accept":        "application/json",
+		},
+		"getInvoices": {
+			"authorization": "Bearer token",
+			"accept":        "application/pdf",
+		},
+	}
+
+	headers := make(map[string]map[string]string)
+	for _, task := range *w.wf.Do {
+		call := task.AsCallHTTPTask()
+		if call == nil {
+			t.Fatalf("expected %s to be a http call", task.Key)
+		}
+		headers[task.Key] = call.With.Headers
+	}
+
+	if !reflect.DeepEqual(headers, expected) {
+		t.Errorf("expected %v, got %v", expected, headers)
+	}
+}
+
+func TestCallHTTPRedirect(t *testing.T) {
+	tests := []struct {
+		status int
+		method string
+		body   string
+	}{
+		// Go's client follows 301 and 302 like 303, as browsers do
+		{status: http.StatusMovedPermanently, method: http.MethodGet},
+		{status: http.StatusFound, method: http.MethodGet},
+		{status: http.StatusSeeOther, method: http.MethodGet},
+		{status: http.StatusTemporaryRedirect, method: http.MethodPost, body: `{"name":"ada"}`},
+		{status: http.StatusPermanentRedirect, method: http.MethodPost, body: `{"name":"ada"}`},
+	}
+
+	// Redirects the POST to /target, which echoes the request it receives
+	handler := func(status int) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/target" {
+				http.Redirect(w, r, "/target", status)
+				return
+			}
+
+			body, _ := io.ReadAll(r.Body)
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = fmt.Fprintf(w, `{"method": %q, "body": %q}`, r.Method, body)
+		}
+	}
+
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("%d followed", test.status), func(t *testing.T) {
+			result, err := runTestHTTPCall(t, `        method: post
+        body:
+          name: ada`, handler(test.status))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if code := result["statusCode"]; code != float64(http.StatusOK) {
+				t.Errorf("expected status %d, got %v", http.StatusOK, code)
+			}
+			expected := map[string]any{"method": test.method, "body": test.body}
+			if !reflect.DeepEqual(result["bodyJSON"], expected) {
+				t.Errorf("expected %v, got %v", expected, result["bodyJSON"])
+			}
+		})
+
+		t.Run(fmt.Sprintf("%d not followed", test.status), func(t *testing.T) {
+			result, err := runTestHTTPCall(t, `        method: post
+        body:
+          name: ada
+        followRedirects: false`, handler(test.status))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if code := result["statusCode"]; code != float64(test.status) {
+				t.Errorf("expected status %d, got %v", test.status, code)
+			}
+			if location := result["location"]; location != "/target" {
+				t.Errorf("expected location /target, got %v", location)
+			}
+		})
+	}
+}
@@ -17,12 +17,19 @@
 package workflow
 
 import (
+	"errors"
 	"fmt"
+	"maps"
+	"slices"
+	"time"
 
 	"github.com/serverlessworkflow/sdk-go/v3/model"
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/workflow"
 )
 
-// A Do task configures a new workflow
+// A top-level Do task configures a new workflow. Any Do tasks inside that
+// are run inline rather than registering more workflows.
 func doTaskImpl(
 	do *model.DoTask,
 	task *model.TaskItem,
@@ -30,10 +37,175 @@ func doTaskImpl(
 ) ([]*TemporalWorkflow, error) {
 	// This doesn't implement the if statement as it
 	// doesn't make sense to conditionally register a workflow
-	temporalWorkflows, err := workflowInst.workflowBuilder(do.Do, task.Key)
+	temporalWorkflows, err := workflowInst.workflowBuilder(do.Do, workflowInst.registeredName(task.Key), true)
 	if err != nil {
 		return nil, fmt.Errorf("error building additional do workflows: %w", err)
 	}
 
+	wf := temporalWorkflows[len(temporalWorkflows)-1]
+	wf.Definition = workflowInst.definitions.do[task.Key]
+	if wf.OnCancel, err = workflowInst.onCancelTasks(workflowInst.onCancel[task.Key], task.Key); err != nil {
+		return nil, err
+	}
+
 	return temporalWorkflows, nil
 }
+
+// An inline Do task runs its tasks sequentially in the current workflow
+func doTaskInlineImpl(
+	do *model.DoTask,
+	task *model.TaskItem,
+	workflowInst *Workflow,
+) (TemporalWorkflowFunc, error) {
+	temporalWorkflows, err := workflowInst.workflowBuilder(do.Do, task.Key, true)
+	if err != nil {
+		return nil, fmt.Errorf("error building inline do tasks: %w", err)
+	}
+
+	// Inline builds only ever return the one workflow
+	wf := temporalWorkflows[len(temporalWorkflows)-1]
+
+	group, err := newDoTaskGroup(task)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(ctx workflow.Context, data *Variables, output map[string]OutputType) error {
+		return group.run(ctx, data, output, func(ctx workflow.Context) error {
+			return wf.runTasks(ctx, data, output)
+		})
+	}, nil
+}
+
+// Temporal's default retry intervals
+const (
+	doRetryBackoffCoefficient = 2.0
+	doRetryInitialInterval    = time.Second
+	doRetryMaximumIntervals   = 100
+)
+
+// An inline do task's timeout and retry policy apply to all of its tasks
+type doTaskGroup struct {
+	key     string
+	retry   *RetryConfig
+	timeout time.Duration
+}
+
+func newDoTaskGroup(task *model.TaskItem) (*doTaskGroup, error) {
+	base := task.GetBase()
+	group := &doTaskGroup{
+		key: task.Key,
+	}
+
+	if base.Timeout != nil && base.Timeout.Timeout != nil && base.Timeout.Timeout.After != nil {
+		timeout, err := ToDuration(base.Timeout.Timeout.After)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing timeout for do task %s: %w", task.Key, err)
+		}
+		if timeout < 0 {
+			return nil, fmt.Errorf("%w: timeout for do task %s", ErrNegativeDuration, task.Key)
+		}
+		group.timeout = timeout
+	}
+
+	retry, err := retryConfigFromMetadata(base.Metadata)
+	if err != nil {
+		return nil, fmt.Errorf("error reading retry for do task %s: %w", task.Key, err)
+	}
+	group.retry = retry
+
+	return group, nil
+}
+
+// Runs the tasks, retrying the whole group on failure. Each attempt starts
+// with the variables and output as they were before the first.
+func (g *doTaskGroup) run(
+	ctx workflow.Context,
+	data *Variables,
+	output map[string]OutputType,
+	fn func(ctx workflow.Context) error,
+) error {
+	logger := workflow.GetLogger(ctx)
+
+	snapshot := maps.Clone(data.Data)
+	outputSnapshot := maps.Clone(output)
+	interval := doRetryInitialInterval
+	coefficient := doRetryBackoffCoefficient
+	if g.retry != nil {
+		if g.retry.InitialInterval > 0 {
+			interval = g.retry.InitialInterval
+		}
+		if g.retry.BackoffCoefficient > 0 {
+			coefficient = g.retry.BackoffCoefficient
+		}
+	}
+	maxInterval := interval * doRetryMaximumIntervals
+	if g.retry != nil && g.retry.MaximumInterval > 0 {
+		maxInterval = g.retry.MaximumInterval
+	}
+
+	for attempt := int32(1); ; attempt++ {
+		err := g.runOnce(ctx, fn)
+		if err == nil || !g.retryable(err) || ctx.Err() != nil {
+			return err
+		}
+		if g.retry.MaximumAttempts > 0 && attempt >= g.retry.MaximumAttempts {
+			return err
+		}
+
+		logger.Warn("Do task failed - retrying", "key", g.key, "attempt", attempt, "interval", interval, "error", err)
+		if err := workflow.Sleep(ctx, interval); err != nil {
+			return err
+		}
+		interval = min(time.Duration(float64(interval)*coefficient), maxInterval)
+		data.Data = maps.Clone(snapshot)
+		// The output is shared with the parent, so is restored in place
+		clear(output)
+		maps.Copy(output, outputSnapshot)
+	}
+}
+
+// Runs the tasks once, cancelling them if they exceed the timeout
+func (g *doTaskGroup) runOnce(ctx workflow.Context, fn func(ctx workflow.Context) error) error {
+	if g.timeout == 0 {
+		return fn(ctx)
+	}
+
+	ctx, cancel := workflow.WithCancel(ctx)
+	defer cancel()
+
+	timedOut := false
+	workflow.Go(ctx, func(ctx workflow.Context) {
+		// The timer is cancelled if the tasks finish first
+		if err := workflow.NewTimer(ctx, g.timeout).Get(ctx, nil); err == nil {
+			timedOut = true
+			cancel()
+		}
+	})
+
+	err := fn(ctx)
+	if timedOut {
+		return temporal.NewApplicationErrorWithCause(
+			fmt.Sprintf("do task %s timed out after %s", g.key, g.timeout),
+			string(DoTimeoutErr),
+			err,
+		)
+	}
+
+	return err
+}
+
+// Errors that can never succeed aren't retried
+func (g *doTaskGroup) retryable(err error) bool {
+	if g.retry == nil {
+		return false
+	}
+
+	var appErr *temporal.ApplicationError
+	if errors.As(err, &appErr) && (appErr.NonRetryable() || slices.Contains(g.retry.NonRetryableErrorTypes, appErr.Type())) {
+		return false
+	}
+
+	var exprErr *ExpressionError
+	return !errors.As(err, &exprErr)
+}
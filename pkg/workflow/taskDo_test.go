@@ -0,0 +1,153 @@
+/*
+ * Copyright 2025 Simon Emms <simon@simonemms.com>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package workflow
+
+import (
+	"errors"
+	"maps"
+	"slices"
+	"testing"
+
+	"github.com/serverlessworkflow/sdk-go/v3/model"
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/workflow"
+)
+
+func TestDoRunsInline(t *testing.T) {
+	recorded := recordTasks(t)
+
+	w := loadTestWorkflow(t, `
+document:
+  dsl: 1.0.0
+  namespace: test
+  name: do
+  version: 0.0.1
+do:
+  - process:
+      do:
+        - first:
+            call: record
+        - group:
+            do:
+              - second:
+                  call: record
+              - nested:
+                  do:
+                    - third:
+                        call: record
+              - fourth:
+                  call: record
+        - fifth:
+            call: record
+`)
+
+	wfs, err := w.BuildWorkflows()
+	if err != nil {
+		t.Fatalf("error building workflows: %v", err)
+	}
+
+	// Only the top-level do is registered, alongside the document's workflow
+	names := make([]string, 0, len(wfs))
+	for _, wf := range wfs {
+		names = append(names, wf.Name)
+	}
+	if expected := []string{"process", "do"}; !slices.Equal(names, expected) {
+		t.Fatalf("expected workflows %v, got %v", expected, names)
+	}
+
+	if _, err := runTestWorkflow(t, w, wfs[0], HTTPData{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []string{"first", "second", "third", "fourth", "fifth"}
+	if !slices.Equal(*recorded, expected) {
+		t.Errorf("expected %v, got %v", expected, *recorded)
+	}
+}
+
+func TestDoGroupTimeout(t *testing.T) {
+	recorded := recordTasks(t)
+
+	// Records the output's keys at the start of each attempt
+	outputs := make([][]string, 0)
+	RegisterTaskHandler("call.outputs", func(task *model.TaskItem, w *Workflow) (TemporalWorkflowFunc, error) {
+		return func(ctx workflow.Context, data *Variables, output map[string]OutputType) error {
+			outputs = append(outputs, slices.Sorted(maps.Keys(output)))
+			return nil
+		}, nil
+	})
+
+	w := loadTestWorkflow(t, `
+document:
+  dsl: 1.0.0
+  namespace: test
+  name: do
+  version: 0.0.1
+do:
+  - process:
+      do:
+        - payment:
+            timeout:
+              after:
+                seconds: 10
+            metadata:
+              retry:
+                initialInterval: 1s
+                maximumAttempts: 2
+            do:
+              - outputs:
+                  call: outputs
+              - authorise:
+                  call: record
+              - authorised:
+                  emit:
+                    event:
+                      with:
+                        source: https://example.com
+                        type: com.example.authorised
+              - pause:
+                  wait:
+                    seconds: 6
+              - capture:
+                  call: record
+              - settle:
+                  wait:
+                    seconds: 6
+        - after:
+            call: record
+`)
+
+	_, err := runTestWorkflow(t, w, buildTestWorkflow(t, w, "process"), HTTPData{})
+
+	var appErr *temporal.ApplicationError
+	if !errors.As(err, &appErr) || appErr.Type() != string(DoTimeoutErr) {
+		t.Fatalf("expected %s, got %v", DoTimeoutErr, err)
+	}
+
+	// Each of the waits is within the timeout, but together they aren't. The
+	// group is retried from the first task and the tasks after it aren't run.
+	expected := []string{"authorise", "capture", "authorise", "capture"}
+	if !slices.Equal(*recorded, expected) {
+		t.Errorf("expected %v, got %v", expected, *recorded)
+	}
+
+	// The output of the failed attempt is discarded before the retry
+	expectedOutputs := [][]string{{}, {}}
+	if !slices.EqualFunc(outputs, expectedOutputs, slices.Equal) {
+		t.Errorf("expected outputs %v, got %v", expectedOutputs, outputs)
+	}
+}
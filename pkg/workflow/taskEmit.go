@@ -0,0 +1,159 @@
+/*
+ * Copyright 2025 Simon Emms <simon@simonemms.com>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package workflow
+
+import (
+	"fmt"
+	"maps"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/serverlessworkflow/sdk-go/v3/model"
+	"go.temporal.io/sdk/workflow"
+)
+
+const cloudEventSpecVersion = "1.0"
+
+// CloudEvent is the structured JSON format of an emitted event
+type CloudEvent struct {
+	SpecVersion     string `json:"specversion"`
+	ID              string `json:"id"`
+	Source          string `json:"source"`
+	Type            string `json:"type"`
+	Time            string `json:"time,omitempty"`
+	Subject         string `json:"subject,omitempty"`
+	DataContentType string `json:"datacontenttype,omitempty"`
+	DataSchema      string `json:"dataschema,omitempty"`
+	Data            any    `json:"data,omitempty"`
+}
+
+// Validate checks the required CloudEvent attributes are set
+func (c *CloudEvent) Validate() error {
+	if c.SpecVersion == "" {
+		return fmt.Errorf("%w: specversion", ErrMissingCloudEventAttribute)
+	}
+	if c.ID == "" {
+		return fmt.Errorf("%w: id", ErrMissingCloudEventAttribute)
+	}
+	if c.Source == "" {
+		return fmt.Errorf("%w: source", ErrMissingCloudEventAttribute)
+	}
+	if c.Type == "" {
+		return fmt.Errorf("%w: type", ErrMissingCloudEventAttribute)
+	}
+	return nil
+}
+
+func validateEmitTask(task *model.EmitTask, key string) error {
+	event := task.Emit.Event.With
+	if event == nil {
+		return fmt.Errorf("%w: %s.emit.event.with", ErrMissingCloudEventAttribute, key)
+	}
+	if event.Source == nil || event.Source.String() == "" {
+		return fmt.Errorf("%w: %s.source", ErrMissingCloudEventAttribute, key)
+	}
+	if event.Type == "" {
+		return fmt.Errorf("%w: %s.type", ErrMissingCloudEventAttribute, key)
+	}
+	return nil
+}
+
+// Build the CloudEvent from the task. The ID and time are generated
+// deterministically if not set so the event is the same on replay.
+func buildCloudEvent(ctx workflow.Context, event *model.EventProperties, data *Variables) (*CloudEvent, error) {
+	ce := &CloudEvent{
+		SpecVersion:     cloudEventSpecVersion,
+		DataContentType: event.DataContentType,
+	}
+
+	var err error
+	if ce.Type, err = ParseVariables(event.Type, data); err != nil {
+		return nil, WithExpressionContext(err, "", "type")
+	}
+	if ce.Source, err = ParseVariables(event.Source.String(), data); err != nil {
+		return nil, WithExpressionContext(err, "", "source")
+	}
+	if ce.Subject, err = ParseVariables(event.Subject, data); err != nil {
+		return nil, WithExpressionContext(err, "", "subject")
+	}
+	if event.DataSchema != nil {
+		if ce.DataSchema, err = ParseVariables(event.DataSchema.String(), data); err != nil {
+			return nil, WithExpressionContext(err, "", "dataschema")
+		}
+	}
+
+	if event.ID != "" {
+		if ce.ID, err = ParseVariables(event.ID, data); err != nil {
+			return nil, WithExpressionContext(err, "", "id")
+		}
+	} else {
+		if err := workflow.SideEffect(ctx, func(ctx workflow.Context) any {
+			return uuid.NewString()
+		}).Get(&ce.ID); err != nil {
+			return nil, fmt.Errorf("unable to generate event id: %w", err)
+		}
+	}
+
+	if event.Time != nil && event.Time.String() != "" {
+		if ce.Time, err = ParseVariables(event.Time.String(), data); err != nil {
+			return nil, WithExpressionContext(err, "", "time")
+		}
+	} else {
+		ce.Time = workflow.Now(ctx).UTC().Format(time.RFC3339Nano)
+	}
+
+	if d, ok := event.Additional["data"]; ok {
+		if ce.Data, err = Interpolate(d, data); err != nil {
+			return nil, WithExpressionContext(err, "", "data")
+		}
+	}
+
+	if err := ce.Validate(); err != nil {
+		return nil, err
+	}
+
+	return ce, nil
+}
+
+func emitTaskImpl(task *model.EmitTask, key string) (TemporalWorkflowFunc, error) {
+	if err := validateEmitTask(task, key); err != nil {
+		return nil, err
+	}
+
+	return func(ctx workflow.Context, data *Variables, output map[string]OutputType) error {
+		logger := workflow.GetLogger(ctx)
+
+		event, err := buildCloudEvent(ctx, task.Emit.Event.With, data)
+		if err != nil {
+			logger.Error("Error building event", "error", err)
+			return fmt.Errorf("error building event: %w", err)
+		}
+
+		logger.Debug("Emitting event", "id", event.ID, "type", event.Type, "source", event.Source)
+
+		publishLocalEvent(ctx, data, event)
+
+		maps.Copy(output, map[string]OutputType{
+			key: {
+				Type: EmitResultType,
+				Data: event,
+			},
+		})
+
+		return nil
+	}, nil
+}
@@ -19,20 +19,108 @@ package workflow
 import (
 	"fmt"
 	"maps"
+	"time"
 
 	"github.com/serverlessworkflow/sdk-go/v3/model"
 	"go.temporal.io/sdk/workflow"
 )
 
+// The metadata key used to set how a fork's results are output
+const forkOutputMetadata = "forkOutput"
+
+const (
+	// Each branch's result is output as "<taskKey>_<branchKey>"
+	forkOutputMap = "map"
+	// The branches' results are output as an array under the task's key, in
+	// the order of the branches
+	forkOutputArray = "array"
+)
+
+// ForkBranchResult is a branch's result when a fork's output is an array
+type ForkBranchResult struct {
+	Name     string                `json:"name"`
+	TimedOut bool                  `json:"timedOut,omitempty"`
+	Data     map[string]OutputType `json:"data,omitempty"`
+}
+
 type forkTaskOutput struct {
-	name string
-	data map[string]OutputType
+	index    int
+	name     string
+	data     map[string]OutputType
+	timedOut bool
+}
+
+// Gets how the fork's results are output, defaulting to a map
+func forkOutputMode(metadata map[string]any) (string, error) {
+	v, ok := metadata[forkOutputMetadata]
+	if !ok || v == nil {
+		return forkOutputMap, nil
+	}
+
+	mode, ok := v.(string)
+	if !ok || (mode != forkOutputMap && mode != forkOutputArray) {
+		return "", fmt.Errorf("%w: %v", ErrInvalidForkOutput, v)
+	}
+	return mode, nil
+}
+
+// Gets the timeout for a fork branch, or 0 if none set
+func forkBranchTimeout(task *model.TaskBase) (time.Duration, error) {
+	if task == nil || task.Timeout == nil || task.Timeout.Timeout == nil || task.Timeout.Timeout.After == nil {
+		return 0, nil
+	}
+	return ToDuration(task.Timeout.Timeout.After)
+}
+
+// Runs a fork branch. If the branch has a timeout, it's cancelled once that
+// passes and is recorded as timed out so the join can continue.
+func runForkBranch(ctx workflow.Context, wf TemporalWorkflowTask, data *Variables) (*forkTaskOutput, error) {
+	logger := workflow.GetLogger(ctx)
+	o := make(map[string]OutputType)
+
+	timeout, err := forkBranchTimeout(wf.TaskBase)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing fork branch timeout: %w", err)
+	}
+	if timeout == 0 {
+		if err := wf.Task(wf.Context(ctx), data, o); err != nil {
+			return nil, err
+		}
+		return &forkTaskOutput{name: wf.Key, data: o}, nil
+	}
+
+	branchCtx, cancel := workflow.WithCancel(ctx)
+	defer cancel()
+
+	future, settable := workflow.NewFuture(branchCtx)
+	workflow.Go(branchCtx, func(ctx workflow.Context) {
+		settable.SetError(wf.Task(wf.Context(ctx), data, o))
+	})
+
+	timedOut := false
+	workflow.NewSelector(ctx).
+		AddFuture(future, func(f workflow.Future) {
+			err = f.Get(ctx, nil)
+		}).
+		AddFuture(workflow.NewTimer(branchCtx, timeout), func(f workflow.Future) {
+			logger.Warn("Fork branch timed out", "task", wf.Key, "timeout", timeout)
+			timedOut = true
+		}).
+		Select(ctx)
+
+	if timedOut {
+		return &forkTaskOutput{name: wf.Key, timedOut: true}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &forkTaskOutput{name: wf.Key, data: o}, nil
 }
 
 // @todo(sje): handle competing forks
 func forkTaskImpl(fork *model.ForkTask, task *model.TaskItem, workflowInst *Workflow) (TemporalWorkflowFunc, error) {
-	childWorkflowName := GenerateChildWorkflowName("fork", task.Key)
-	temporalWorkflows, err := workflowInst.workflowBuilder(fork.Fork.Branches, childWorkflowName)
+	childWorkflowName := workflowInst.GenerateChildWorkflowName("fork", task.Key)
+	temporalWorkflows, err := workflowInst.workflowBuilder(fork.Fork.Branches, childWorkflowName, true)
 	if err != nil {
 		return nil, fmt.Errorf("error building forked workflow: %w", err)
 	}
@@ -42,32 +130,39 @@ func forkTaskImpl(fork *model.ForkTask, task *model.TaskItem, workflowInst *Work
 		n += len(t.Tasks)
 	}
 
+	outputMode, err := forkOutputMode(task.GetBase().Metadata)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", err, task.Key)
+	}
+
 	return func(ctx workflow.Context, data *Variables, output map[string]OutputType) error {
 		logger := workflow.GetLogger(ctx)
 		logger.Debug("Forking a task", "isCompeting", fork.Fork.Compete)
 
 		chunkResultChannel := workflow.NewChannel(ctx)
 
+		branches := 0
 		for _, temporalWorkflow := range temporalWorkflows {
 			for _, wf := range temporalWorkflow.Tasks {
-				workflow.Go(ctx, func(ctx workflow.Context) {
-					o := make(map[string]OutputType)
+				index := branches
+				branches++
 
-					err := wf.Task(ctx, data, o)
+				workflow.Go(ctx, func(ctx workflow.Context) {
+					result, err := runForkBranch(ctx, wf, data)
 					if err != nil {
 						logger.Error("Error handling Temporal task", "error", err, "task", wf.Key)
 						chunkResultChannel.Send(ctx, err)
 						return
 					}
 
-					chunkResultChannel.Send(ctx, forkTaskOutput{
-						name: wf.Key,
-						data: o,
-					})
+					result.index = index
+					chunkResultChannel.Send(ctx, *result)
 				})
 			}
 		}
 
+		results := make([]ForkBranchResult, branches)
+
 		for _, temporalWorkflow := range temporalWorkflows {
 			for range temporalWorkflow.Tasks {
 				var v any
@@ -79,6 +174,22 @@ func forkTaskImpl(fork *model.ForkTask, task *model.TaskItem, workflowInst *Work
 						return result
 					}
 				case forkTaskOutput:
+					if outputMode == forkOutputArray {
+						results[result.index] = ForkBranchResult{
+							Name:     result.name,
+							TimedOut: result.timedOut,
+							Data:     result.data,
+						}
+						continue
+					}
+
+					if result.timedOut {
+						output[fmt.Sprintf("%s_%s", task.Key, result.name)] = OutputType{
+							Type: ForkTimeoutResultType,
+						}
+						continue
+					}
+
 					maps.Copy(output, map[string]OutputType{
 						fmt.Sprintf("%s_%s", task.Key, result.name): {
 							Type: ForkResultType,
@@ -89,6 +200,13 @@ func forkTaskImpl(fork *model.ForkTask, task *model.TaskItem, workflowInst *Work
 			}
 		}
 
+		if outputMode == forkOutputArray {
+			output[task.Key] = OutputType{
+				Type: ForkResultType,
+				Data: results,
+			}
+		}
+
 		return nil
 	}, nil
 }
@@ -0,0 +1,66 @@
+/*
+ * Copyright 2025 Simon Emms <simon@simonemms.com>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package workflow
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestForkBranchWithDo(t *testing.T) {
+	recorded := recordTasks(t)
+
+	w := loadTestWorkflow(t, `
+document:
+  dsl: 1.0.0
+  namespace: test
+  name: fork
+  version: 0.0.1
+do:
+  - fork:
+      fork:
+        branches:
+          - slow:
+              do:
+                - slow1:
+                    call: record
+                - pause:
+                    wait:
+                      seconds: 1
+                - slow2:
+                    call: record
+                - slow3:
+                    call: record
+          - fast:
+              do:
+                - fast1:
+                    call: record
+                - fast2:
+                    call: record
+`)
+
+	if _, err := runTestWorkflow(t, w, buildTestWorkflow(t, w, "fork"), HTTPData{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// The fast branch runs while the slow one waits, but each branch's tasks
+	// stay in order
+	expected := []string{"slow1", "fast1", "fast2", "slow2", "slow3"}
+	if !slices.Equal(*recorded, expected) {
+		t.Errorf("expected %v, got %v", expected, *recorded)
+	}
+}
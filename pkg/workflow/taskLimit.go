@@ -0,0 +1,69 @@
+/*
+ * Copyright 2025 Simon Emms <simon@simonemms.com>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package workflow
+
+import (
+	"fmt"
+
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/workflow"
+)
+
+// DefaultMaxTasks is the default number of tasks a workflow can run before
+// it's failed. This is generous, but stops a runaway workflow growing its
+// history until it times out.
+const DefaultMaxTasks = 10000
+
+// WithMaxTasks sets the number of tasks a workflow can run, including those
+// inside a do, fork, try or listen. 0 is unlimited.
+func WithMaxTasks(maxTasks int) Option {
+	return func(w *Workflow) {
+		w.maxTasks = maxTasks
+	}
+}
+
+type taskLimit struct {
+	count int
+	max   int
+}
+
+type taskLimitKey struct{}
+
+// Sets the task limit on the context, so tasks in inline workflows count too
+func withTaskLimit(ctx workflow.Context, maxTasks int) workflow.Context {
+	if maxTasks <= 0 {
+		return ctx
+	}
+
+	return workflow.WithValue(ctx, taskLimitKey{}, &taskLimit{max: maxTasks})
+}
+
+// Counts a task being run, failing once the limit's exceeded
+func countTask(ctx workflow.Context, key string) error {
+	l, ok := ctx.Value(taskLimitKey{}).(*taskLimit)
+	if !ok || l == nil {
+		return nil
+	}
+
+	l.count++
+	if l.count > l.max {
+		err := fmt.Errorf("%w: %d tasks run, stopped at %s", ErrTaskLimitExceeded, l.max, key)
+		return temporal.NewNonRetryableApplicationError(err.Error(), string(TaskLimitErr), err)
+	}
+
+	return nil
+}
@@ -17,10 +17,13 @@
 package workflow
 
 import (
+	"encoding/json"
 	"fmt"
 	"slices"
+	"strings"
 	"time"
 
+	"github.com/itchyny/gojq"
 	"github.com/serverlessworkflow/sdk-go/v3/model"
 	"go.temporal.io/api/enums/v1"
 	"go.temporal.io/sdk/temporal"
@@ -33,7 +36,10 @@ type TaskListenResponse struct {
 	TaskComplete  bool   `json:"taskComplete"`
 }
 
-type ListenTaskType string
+type (
+	ListenTaskType   string
+	QueryContentType string
+)
 
 const (
 	ListenTaskTypeQuery  ListenTaskType = "query"
@@ -41,30 +47,58 @@ const (
 	ListenTaskTypeUpdate ListenTaskType = "update"
 )
 
+const (
+	QueryContentTypeJSON       QueryContentType = "application/json"
+	QueryContentTypeText       QueryContentType = "text/plain"
+	QueryContentTypeTextYAML   QueryContentType = "text/yaml"
+	QueryContentTypeYAML       QueryContentType = "application/yaml"
+	QueryContentTypeYAMLLegacy QueryContentType = "application/x-yaml"
+)
+
+// Converts the interpolated query data to a Golang type based on the
+// datacontenttype. Anything without a known content type is returned as-is
+func convertQueryData(value any, contentType string) (any, error) {
+	switch QueryContentType(contentType) {
+	case QueryContentTypeJSON:
+		return FromJSON(value)
+	case QueryContentTypeYAML, QueryContentTypeYAMLLegacy, QueryContentTypeTextYAML:
+		return FromYAML(value)
+	default:
+		return value, nil
+	}
+}
+
 func configureQueryListener(ctx workflow.Context, event *model.EventFilter, data *Variables) error {
 	logger := workflow.GetLogger(ctx)
 
 	handler := func() (any, error) {
 		logger.Debug("Received query")
 
+		if p, ok := event.With.Additional["projection"]; ok {
+			// Return a computed subset of the variables
+			value, err := EvaluateJQ(p.(string), "projection", data)
+			if err != nil {
+				err = WithExpressionContext(err, event.With.ID, "projection")
+				logger.Error("Error running query projection", "error", err)
+				return nil, err
+			}
+
+			return value, nil
+		}
+
 		if d, ok := event.With.Additional["data"]; ok {
 			value, err := Interpolate(d, data)
 			if err != nil {
+				err = WithExpressionContext(err, event.With.ID, "data")
 				logger.Error("Error interpolating data", "error", err)
 				return nil, err
 			}
 
 			// Convert the output
-			if event.With.DataContentType == "application/json" {
-				logger.Debug("Converting query to Golang type")
-
-				// Convert YAML to Golang type
-				var err error
-				value, err = FromYAML(value)
-				if err != nil {
-					logger.Error("Cannot convert to Golang type - ensure query data is a string for interpolation", "error", err)
-					return nil, fmt.Errorf("ensure query data is a string for interpolation: %w", err)
-				}
+			value, err = convertQueryData(value, event.With.DataContentType)
+			if err != nil {
+				logger.Error("Cannot convert to Golang type - ensure query data is a string for interpolation", "error", err, "contentType", event.With.DataContentType)
+				return nil, fmt.Errorf("ensure query data is a string for interpolation: %w", err)
 			}
 
 			return value, nil
@@ -77,37 +111,192 @@ func configureQueryListener(ctx workflow.Context, event *model.EventFilter, data
 	return workflow.SetQueryHandlerWithOptions(ctx, event.With.ID, handler, workflow.QueryHandlerOptions{})
 }
 
-func configureSignalListener(ctx workflow.Context, event *model.EventFilter, _ *Variables) error {
+// Versions counting signals towards the listen's threshold, so runs started
+// before it replay waiting for each signal in turn
+const listenSignalQuorumChange = "listen-signal-quorum"
+
+// Signals are received either from the Temporal server or from an event
+// emitted in this workflow, such as from another fork branch. The emitted
+// event's type must match the signal ID. This waits for the signal, so is only
+// used by runs started before signals were counted towards the threshold.
+func configureSignalListener(
+	ctx workflow.Context,
+	event *model.EventFilter,
+	data *Variables,
+	guard func() error,
+	guardFuture workflow.Future,
+	onEvent func(payload any),
+) error {
 	logger := workflow.GetLogger(ctx)
 	logger.Debug("Creating signal", "signal", event.With.ID)
 
-	r := workflow.GetSignalChannel(ctx, event.With.ID)
+	var received, timedOut bool
+	var receiveErr, guardErr error
+
+	selector := workflow.NewSelector(ctx)
+	selector.AddReceive(workflow.GetSignalChannel(ctx, event.With.ID), func(c workflow.ReceiveChannel, more bool) {
+		var payload any
+		c.Receive(ctx, &payload)
+		onEvent(payload)
+		received = true
+	})
+	selector.AddReceive(data.localEventChannel(ctx, event.With.ID), func(c workflow.ReceiveChannel, more bool) {
+		var e *CloudEvent
+		c.Receive(ctx, &e)
+
+		ok, err := eventCorrelates(e, event, data)
+		if err != nil {
+			receiveErr = err
+			return
+		}
+		if !ok {
+			logger.Debug("Local event doesn't correlate - ignoring", "signal", event.With.ID, "id", e.ID)
+			return
+		}
+		logger.Debug("Local event received", "signal", event.With.ID, "id", e.ID)
+		onEvent(e.Data)
+		received = true
+	})
 
-	// @todo(sje): allow data to be received via signal
-	// @todo(sje): ignore if timeout is set to 0 or "0"
+	// A zero timeout waits forever
 	if timeout, ok := event.With.Additional["timeout"]; ok {
-		logger.Debug("Adding timeout to signal receiver", "timeout", timeout)
-		t, err := time.ParseDuration(timeout.(string))
+		t, err := ParseAnyDuration(fmt.Sprint(timeout))
 		if err != nil {
 			logger.Error("Unable to parse duration: %w", err)
 			return fmt.Errorf("unable to parse duration: %w", err)
 		}
+		if t < 0 {
+			return fmt.Errorf("%w: signal timeout %s", ErrNegativeDuration, t)
+		}
+
+		if t > 0 {
+			logger.Debug("Adding timeout to signal receiver", "timeout", t)
 
-		received, _ := r.ReceiveWithTimeout(ctx, t, nil)
-		if !received {
-			logger.Error("Signal not received within timeout")
-			return fmt.Errorf("signal not received within timeout")
+			timerCtx, cancelTimer := workflow.WithCancel(ctx)
+			defer cancelTimer()
+
+			selector.AddFuture(workflow.NewTimer(timerCtx, t), func(f workflow.Future) {
+				timedOut = true
+			})
 		}
-		return nil
+	}
+
+	if err := guard(); err != nil {
+		logger.Warn("Guard no longer holds - listen abandoned", "error", err)
+		return err
+	}
+	if guardFuture != nil {
+		selector.AddFuture(guardFuture, func(f workflow.Future) {
+			guardErr = f.Get(ctx, nil)
+		})
 	}
 
 	logger.Debug("Listening for signal")
-	_ = r.Receive(ctx, nil)
+	for !received && !timedOut && receiveErr == nil && guardErr == nil {
+		selector.Select(ctx)
+	}
+
+	if guardErr != nil {
+		logger.Warn("Guard no longer holds - listen abandoned", "error", guardErr)
+		return guardErr
+	}
+
+	if receiveErr != nil {
+		logger.Error("Error correlating event", "error", receiveErr)
+		return fmt.Errorf("error correlating event: %w", receiveErr)
+	}
+
+	if !received {
+		logger.Error("Signal not received within timeout")
+		return fmt.Errorf("signal not received within timeout")
+	}
 
 	return nil
 }
 
-func configureUpdateListener(ctx workflow.Context, event *model.EventFilter, data *Variables, onSuccess func()) error {
+type signalHandlers struct {
+	onReceived func(i int, payload any)
+	onTimeout  func(i int)
+	onError    func(err error)
+}
+
+// Receives the signals at the indexes on one selector, so each can count
+// towards the listen's threshold whatever order they arrive in. They're
+// received in the background until the context is cancelled.
+func configureSignalListeners(
+	ctx workflow.Context,
+	events []*model.EventFilter,
+	indexes []int,
+	data *Variables,
+	handlers signalHandlers,
+) error {
+	logger := workflow.GetLogger(ctx)
+
+	done := false
+	selector := workflow.NewSelector(ctx)
+	selector.AddReceive(ctx.Done(), func(c workflow.ReceiveChannel, more bool) {
+		done = true
+	})
+
+	for _, i := range indexes {
+		event := events[i]
+		logger.Debug("Creating signal", "signal", event.With.ID)
+
+		selector.AddReceive(workflow.GetSignalChannel(ctx, event.With.ID), func(c workflow.ReceiveChannel, more bool) {
+			var payload any
+			c.Receive(ctx, &payload)
+			handlers.onReceived(i, payload)
+		})
+		selector.AddReceive(data.localEventChannel(ctx, event.With.ID), func(c workflow.ReceiveChannel, more bool) {
+			var e *CloudEvent
+			c.Receive(ctx, &e)
+
+			ok, err := eventCorrelates(e, event, data)
+			if err != nil {
+				handlers.onError(err)
+				return
+			}
+			if !ok {
+				logger.Debug("Local event doesn't correlate - ignoring", "signal", event.With.ID, "id", e.ID)
+				return
+			}
+			logger.Debug("Local event received", "signal", event.With.ID, "id", e.ID)
+			handlers.onReceived(i, e.Data)
+		})
+
+		// A zero timeout waits forever
+		timeout, ok := event.With.Additional["timeout"]
+		if !ok {
+			continue
+		}
+		t, err := ParseAnyDuration(fmt.Sprint(timeout))
+		if err != nil {
+			return fmt.Errorf("unable to parse duration: %w", err)
+		}
+		if t < 0 {
+			return fmt.Errorf("%w: signal timeout %s", ErrNegativeDuration, t)
+		}
+		if t > 0 {
+			logger.Debug("Adding timeout to signal receiver", "signal", event.With.ID, "timeout", t)
+			selector.AddFuture(workflow.NewTimer(ctx, t), func(f workflow.Future) {
+				// The timer's cancelled once the listen is complete
+				if f.Get(ctx, nil) == nil {
+					handlers.onTimeout(i)
+				}
+			})
+		}
+	}
+
+	workflow.Go(ctx, func(ctx workflow.Context) {
+		for !done {
+			selector.Select(ctx)
+		}
+	})
+
+	return nil
+}
+
+func configureUpdateListener(ctx workflow.Context, event *model.EventFilter, data *Variables, onSuccess func(args HTTPData)) error {
 	logger := workflow.GetLogger(ctx)
 
 	handler := func(ctx workflow.Context, args HTTPData) (*TaskListenResponse, error) {
@@ -130,7 +319,7 @@ func configureUpdateListener(ctx workflow.Context, event *model.EventFilter, dat
 			}
 		}
 
-		onSuccess()
+		onSuccess(args)
 
 		resp.EventComplete = true
 
@@ -147,6 +336,7 @@ func configureUpdateListener(ctx workflow.Context, event *model.EventFilter, dat
 						return fmt.Errorf("if is not a string: %+v", d)
 					} else {
 						if _, err := ParseVariables(s, data); err != nil {
+							err = WithExpressionContext(err, event.With.ID, "if")
 							logger.Error("cannot parse data", "error", err)
 							return fmt.Errorf("cannot parse data: %w", err)
 						}
@@ -159,14 +349,19 @@ func configureUpdateListener(ctx workflow.Context, event *model.EventFilter, dat
 	)
 }
 
-func listenConfigure(task *model.ListenTask, key string) (events []*model.EventFilter, isAll bool, err error) {
+func listenConfigure(task *model.ListenTask, key string, jqDefs []*gojq.FuncDef) (events []*model.EventFilter, isAll bool, err error) {
 	isAll = false
 	events = make([]*model.EventFilter, 0)
 
+	if err = validateListenStrategy(task.Listen.To); err != nil {
+		err = fmt.Errorf("%w: %s", err, key)
+		return events, isAll, err
+	}
+
 	if len(task.Listen.To.All) > 0 {
 		isAll = true
 		for k, i := range task.Listen.To.All {
-			if err = validateEventFilter(i); err != nil {
+			if err = validateEventFilter(i, jqDefs); err != nil {
 				err = fmt.Errorf("%w: %s.%d", err, key, k)
 				return events, isAll, err
 			}
@@ -174,14 +369,14 @@ func listenConfigure(task *model.ListenTask, key string) (events []*model.EventF
 		}
 	} else if len(task.Listen.To.Any) > 0 {
 		for k, i := range task.Listen.To.Any {
-			if err = validateEventFilter(i); err != nil {
+			if err = validateEventFilter(i, jqDefs); err != nil {
 				err = fmt.Errorf("%w: %s.%d", err, key, k)
 				return events, isAll, err
 			}
 			events = append(events, i)
 		}
 	} else if task.Listen.To.One != nil {
-		if err = validateEventFilter(task.Listen.To.One); err != nil {
+		if err = validateEventFilter(task.Listen.To.One, jqDefs); err != nil {
 			err = fmt.Errorf("%w: %s", err, key)
 			return events, isAll, err
 		}
@@ -197,45 +392,140 @@ func listenConfigure(task *model.ListenTask, key string) (events []*model.EventF
 	return events, isAll, err
 }
 
-func listenTaskImpl(task *model.ListenTask, key string) (TemporalWorkflowFunc, error) {
-	events, isAll, err := listenConfigure(task, key)
+func listenTaskImpl(task *model.ListenTask, key string, workflowInst *Workflow) (TemporalWorkflowFunc, error) {
+	events, isAll, err := listenConfigure(task, key, workflowInst.jqDefs)
+	if err != nil {
+		return nil, err
+	}
+
+	ext := workflowInst.listenExtensions[key]
+	if ext == nil {
+		ext = &ListenExtensions{}
+	}
+
+	foreach, err := listenForeachImpl(ext.Foreach, key, workflowInst)
+	if err != nil {
+		return nil, err
+	}
+
+	guard, err := taskGuard(key, task.Metadata, workflowInst.jqDefs)
 	if err != nil {
 		return nil, err
 	}
 
+	// Queries are answered rather than received, so don't count towards
+	// completing the listen
+	countable := 0
+	hasSignals := false
+	for _, event := range events {
+		switch ListenTaskType(event.With.Type) {
+		case ListenTaskTypeSignal:
+			hasSignals = true
+			countable++
+		case ListenTaskTypeUpdate:
+			countable++
+		}
+	}
+
+	// Defaults to waiting for all or any events
+	threshold := 1
+	if isAll {
+		threshold = countable
+	}
+	if amount := ext.To.Amount; amount != 0 {
+		if amount < 0 || amount > countable {
+			return nil, fmt.Errorf("%w: %s.listen.to.amount must be between 1 and %d", ErrInvalidListenAmount, key, countable)
+		}
+		threshold = amount
+	}
+
 	return func(ctx workflow.Context, data *Variables, output map[string]OutputType) error {
 		logger := workflow.GetLogger(ctx)
 		logger.Debug("Registering listeners")
 
-		isAllComplete := make([]bool, 0)
-		isAnyComplete := false
-		await := false
+		listeners, err := resolveEventIDs(events, key, data)
+		if err != nil {
+			logger.Error("Error resolving listener IDs", "error", err)
+			return err
+		}
+
+		// Track which events are complete and keep a count so checking for
+		// completion doesn't need to scan every event
+		isEventComplete := make([]bool, len(listeners))
+		completeCount := 0
+
+		// Received events are queued and processed in order by the foreach
+		queue := make([]any, 0)
+		processed := 0
+		onEvent := func(payload any) {
+			if foreach != nil {
+				queue = append(queue, payload)
+			}
+		}
+		processQueue := func() error {
+			for len(queue) > 0 {
+				payload := queue[0]
+				queue = queue[1:]
+				if err := foreach(ctx, data, output, payload, processed); err != nil {
+					return err
+				}
+				processed++
+			}
+			return nil
+		}
+
+		// The guard is re-checked while waiting, abandoning the listen if it
+		// no longer holds
+		check := guardCheck(guard, key, data)
+		guardCtx, cancelGuard := workflow.WithCancel(ctx)
+		defer cancelGuard()
+		guardFuture := watchGuard(guardCtx, guard, check)
+
+		// Queries are registered first as a signal listener blocks until it's
+		// received, which would leave any later query unanswered until then
+		for _, event := range listeners {
+			if ListenTaskType(event.With.Type) != ListenTaskTypeQuery {
+				continue
+			}
+			if err := configureQueryListener(ctx, event, data); err != nil {
+				logger.Error("Error setting query", "id", event.With.ID, "error", err)
+				return fmt.Errorf("error setting query: %w", err)
+			}
+		}
 
-		for i, event := range events {
-			if isAll {
-				isAllComplete = append(isAllComplete, false)
+		// Signals are counted towards the threshold like updates. Runs started
+		// before this wait for each signal in turn instead.
+		quorum := hasSignals && workflow.GetVersion(ctx, listenSignalQuorumChange, workflow.DefaultVersion, 1) != workflow.DefaultVersion
+
+		markComplete := func(i int) {
+			if !isEventComplete[i] {
+				isEventComplete[i] = true
+				completeCount++
 			}
+		}
 
+		hasUpdates := false
+		signals := make([]int, 0)
+		for i, event := range listeners {
 			switch ListenTaskType(event.With.Type) {
-			case ListenTaskTypeQuery:
-				if err := configureQueryListener(ctx, event, data); err != nil {
-					logger.Error("Error setting query", "id", event.With.ID, "error", err)
-					return fmt.Errorf("error setting query: %w", err)
-				}
 			case ListenTaskTypeSignal:
-				if err := configureSignalListener(ctx, event, data); err != nil {
+				if quorum {
+					signals = append(signals, i)
+					continue
+				}
+				if err := configureSignalListener(ctx, event, data, check, guardFuture, onEvent); err != nil {
 					logger.Error("Error setting signal", "id", event.With.ID, "error", err)
 					return fmt.Errorf("error setting signal: %w", err)
 				}
+				if err := processQueue(); err != nil {
+					return err
+				}
 			case ListenTaskTypeUpdate:
-				await = true
-				if err := configureUpdateListener(ctx, event, data, func() {
+				hasUpdates = true
+				if err := configureUpdateListener(ctx, event, data, func(args HTTPData) {
 					logger.Debug("Listen event received", "event", event.With.ID)
-					if isAll {
-						isAllComplete[i] = true
-					} else {
-						isAnyComplete = true
-					}
+					onEvent(args)
+					markComplete(i)
 				}); err != nil {
 					logger.Error("Error setting update", "id", event.With.ID, "error", err)
 					return fmt.Errorf("error setting update: %w", err)
@@ -243,11 +533,62 @@ func listenTaskImpl(task *model.ListenTask, key string) (TemporalWorkflowFunc, e
 			}
 		}
 
-		// @todo(sje): figure out a way of customising the timeout
-		timeout := time.Hour
+		// A signal that times out can't be counted, so the listen fails once
+		// the threshold can no longer be reached
+		var signalErr error
+		if len(signals) > 0 {
+			signalCtx, cancelSignals := workflow.WithCancel(ctx)
+			defer cancelSignals()
+
+			timedOut := 0
+			err := configureSignalListeners(signalCtx, listeners, signals, data, signalHandlers{
+				onReceived: func(i int, payload any) {
+					logger.Debug("Listen event received", "event", listeners[i].With.ID)
+					onEvent(payload)
+					markComplete(i)
+				},
+				onTimeout: func(i int) {
+					if isEventComplete[i] {
+						return
+					}
+					timedOut++
+					if countable-timedOut < threshold {
+						signalErr = fmt.Errorf("signal not received within timeout: %s", listeners[i].With.ID)
+					}
+				},
+				onError: func(err error) {
+					signalErr = fmt.Errorf("error correlating event: %w", err)
+				},
+			})
+			if err != nil {
+				logger.Error("Error setting signals", "error", err)
+				return fmt.Errorf("error setting signal: %w", err)
+			}
+		}
+
+		if hasUpdates || len(signals) > 0 {
+			// @todo(sje): figure out a way of customising the timeout
+			timeout := time.Hour
+			if !hasUpdates {
+				// Signals wait forever, unless they have their own timeout
+				timeout = 0
+			}
+
+			isComplete := func() bool {
+				logger.Debug("Waiting for listener(s) to complete", "complete", completeCount, "threshold", threshold)
+				return completeCount >= threshold
+			}
+			hasQueued := func() bool {
+				return len(queue) > 0
+			}
+			abandon := func() error {
+				if signalErr != nil {
+					return signalErr
+				}
+				return check()
+			}
 
-		if await {
-			if err := waitForListener(ctx, timeout, isAll, isAnyComplete, isAllComplete); err != nil {
+			if err := waitForListener(ctx, timeout, isComplete, hasQueued, abandon, processQueue); err != nil {
 				return err
 			}
 		}
@@ -256,31 +597,136 @@ func listenTaskImpl(task *model.ListenTask, key string) (TemporalWorkflowFunc, e
 	}, nil
 }
 
-func waitForListener(ctx workflow.Context, timeout time.Duration, isAll, isAnyComplete bool, isAllComplete []bool) error {
+// Waits until the listener is complete, processing any queued events as they
+// arrive. A zero timeout waits forever. Query handlers are answered by the SDK
+// while the workflow is blocked here, so a parked workflow can still be queried.
+// If abandon returns an error, such as the guard no longer holding, the wait is
+// abandoned with it.
+func waitForListener(
+	ctx workflow.Context,
+	timeout time.Duration,
+	isComplete, hasQueued func() bool,
+	abandon func() error,
+	processQueue func() error,
+) error {
 	logger := workflow.GetLogger(ctx)
-	logger.Debug("Listening for updates", "timeout", timeout)
+	logger.Debug("Listening for events", "timeout", timeout)
 
-	if ok, err := workflow.AwaitWithTimeout(ctx, timeout, func() bool {
-		// Calculate if the task if finished
-		if isAll {
-			logger.Debug("Waiting for listener(s) to complete", "complete", isAllComplete)
-			return SlicesEqual(isAllComplete, true)
+	if timeout < 0 {
+		return fmt.Errorf("%w: listen timeout %s", ErrNegativeDuration, timeout)
+	}
+
+	deadline := workflow.Now(ctx).Add(timeout)
+	var abandonErr error
+	condition := func() bool {
+		abandonErr = abandon()
+		return hasQueued() || isComplete() || abandonErr != nil
+	}
+
+	for {
+		ok := true
+		var err error
+		if timeout == 0 {
+			err = workflow.Await(ctx, condition)
 		} else {
-			logger.Debug("Waiting for listener to complete", "complete", isAnyComplete)
-			return isAnyComplete
+			ok, err = workflow.AwaitWithTimeout(ctx, deadline.Sub(workflow.Now(ctx)), condition)
+		}
+		if err != nil {
+			logger.Error("Error waiting", "error", err)
+			return fmt.Errorf("error waiting: %w", err)
+		}
+
+		if err := processQueue(); err != nil {
+			return err
+		}
+
+		// Abandoning wins if the same event completes the listen
+		if abandonErr != nil {
+			logger.Warn("Listen abandoned", "error", abandonErr)
+			return abandonErr
+		}
+
+		if isComplete() {
+			return nil
+		}
+
+		if !ok {
+			logger.Warn("Await timeout")
+			return temporal.NewTimeoutError(*enums.TIMEOUT_TYPE_SCHEDULE_TO_START.Enum(), nil)
 		}
-	}); err != nil {
-		logger.Error("Error waiting", "error", err)
-		return fmt.Errorf("error waiting: %w", err)
-	} else if !ok {
-		logger.Warn("Await timeout")
-		return temporal.NewTimeoutError(*enums.TIMEOUT_TYPE_SCHEDULE_TO_START.Enum(), nil)
+	}
+}
+
+// Resolves each event's ID against the variables when the listeners are
+// registered, so the handler's name can come from the input, eg
+// approval-{{ .orderId }}. The events are copied so the definition isn't
+// changed for other runs.
+func resolveEventIDs(events []*model.EventFilter, key string, data *Variables) ([]*model.EventFilter, error) {
+	resolved := make([]*model.EventFilter, 0, len(events))
+	for _, event := range events {
+		id := event.With.ID
+
+		switch {
+		case isJQExpression(id):
+			v, err := EvaluateJQ(id, "with.id", data)
+			if err != nil {
+				return nil, WithExpressionContext(err, key, "with.id")
+			}
+			id = ""
+			if v != nil {
+				id = fmt.Sprint(v)
+			}
+		case strings.Contains(id, "{{"):
+			var err error
+			if id, err = ParseVariables(id, data); err != nil {
+				return nil, WithExpressionContext(err, key, "with.id")
+			}
+		default:
+			resolved = append(resolved, event)
+			continue
+		}
+
+		if strings.TrimSpace(id) == "" {
+			return nil, fmt.Errorf("%w: %s resolved to an empty id", ErrUnsetListenIDTask, key)
+		}
+
+		with := *event.With
+		with.ID = id
+		e := *event
+		e.With = &with
+		resolved = append(resolved, &e)
+	}
+
+	return resolved, nil
+}
+
+func validateListenStrategy(to *model.EventConsumptionStrategy) error {
+	if to == nil {
+		return ErrUnsetListenIDTask
+	}
+
+	set := make([]string, 0)
+	if len(to.All) > 0 {
+		set = append(set, "all")
+	}
+	if len(to.Any) > 0 {
+		set = append(set, "any")
+	}
+	if to.One != nil {
+		set = append(set, "one")
+	}
+	if to.Until != nil {
+		set = append(set, "until")
+	}
+
+	if len(set) > 1 {
+		return fmt.Errorf("%w (%s)", ErrMultipleListenStrategies, strings.Join(set, ", "))
 	}
 
 	return nil
 }
 
-func validateEventFilter(event *model.EventFilter) error {
+func validateEventFilter(event *model.EventFilter, jqDefs []*gojq.FuncDef) error {
 	if event.With.ID == "" {
 		return ErrUnsetListenIDTask
 	}
@@ -298,5 +744,178 @@ func validateEventFilter(event *model.EventFilter) error {
 		return ErrUnknownListenTypeTask
 	}
 
+	if ListenTaskType(event.With.Type) == ListenTaskTypeQuery {
+		if err := validateQueryProjection(event, jqDefs); err != nil {
+			return err
+		}
+	}
+
+	if ListenTaskType(event.With.Type) == ListenTaskTypeQuery && event.With.DataContentType != "" {
+		validContentTypes := []QueryContentType{
+			QueryContentTypeJSON,
+			QueryContentTypeText,
+			QueryContentTypeTextYAML,
+			QueryContentTypeYAML,
+			QueryContentTypeYAMLLegacy,
+		}
+
+		if !slices.Contains(validContentTypes, QueryContentType(event.With.DataContentType)) {
+			return fmt.Errorf("%w: %s", ErrUnsupportedContentType, event.With.DataContentType)
+		}
+	}
+
 	return nil
 }
+
+func validateQueryProjection(event *model.EventFilter, jqDefs []*gojq.FuncDef) error {
+	p, ok := event.With.Additional["projection"]
+	if !ok {
+		return nil
+	}
+
+	if _, ok := event.With.Additional["data"]; ok {
+		return ErrQueryProjectionAndData
+	}
+
+	expression, ok := p.(string)
+	if !ok {
+		return fmt.Errorf("%w: projection", ErrNotString)
+	}
+
+	if _, err := parseJQ(model.SanitizeExpr(expression), "projection", jqDefs...); err != nil {
+		return WithExpressionContext(err, event.With.ID, "projection")
+	}
+
+	return nil
+}
+
+// ListenForeach is the listen task's "foreach", which runs the tasks for each
+// event received. This isn't in the SDK's model, so it's read from the raw
+// workflow definition.
+type ListenForeach struct {
+	Item string          `json:"item,omitempty"`
+	At   string          `json:"at,omitempty"`
+	Do   *model.TaskList `json:"do,omitempty"`
+	// The start to close timeout of the activities run for each event, as
+	// they may run long after the workflow's started. This wins over the task
+	// type's activity options, but not a timeout set on the task. Defaults to
+	// the workflow's timeout.
+	ActivityTimeout string `json:"activityTimeout,omitempty"`
+}
+
+type listenForeachFunc func(ctx workflow.Context, data *Variables, output map[string]OutputType, payload any, index int) error
+
+func listenForeachImpl(foreach *ListenForeach, key string, workflowInst *Workflow) (listenForeachFunc, error) {
+	if foreach == nil {
+		return nil, nil
+	}
+	if foreach.Do == nil {
+		return nil, fmt.Errorf("%w: %s.listen.foreach.do", ErrUnsetListenForeachDo, key)
+	}
+
+	item := foreach.Item
+	if item == "" {
+		item = "item"
+	}
+	at := foreach.At
+	if at == "" {
+		at = "index"
+	}
+
+	var activityTimeout time.Duration
+	if foreach.ActivityTimeout != "" {
+		t, err := ParseAnyDuration(foreach.ActivityTimeout)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s.listen.foreach.activityTimeout", err, key)
+		}
+		if t < 0 {
+			return nil, fmt.Errorf("%w: %s.listen.foreach.activityTimeout %s", ErrNegativeDuration, key, t)
+		}
+		activityTimeout = t
+	}
+
+	// The tasks are built with the timeout, including those in a nested group.
+	// An inner foreach without its own timeout keeps the outer one.
+	outerTimeout := workflowInst.foreachTimeout
+	if activityTimeout > 0 {
+		workflowInst.foreachTimeout = activityTimeout
+	}
+	temporalWorkflows, err := workflowInst.workflowBuilder(foreach.Do, key, true)
+	workflowInst.foreachTimeout = outerTimeout
+	if err != nil {
+		return nil, fmt.Errorf("error building listen foreach tasks: %w", err)
+	}
+	wf := temporalWorkflows[len(temporalWorkflows)-1]
+
+	return func(ctx workflow.Context, data *Variables, output map[string]OutputType, payload any, index int) error {
+		logger := workflow.GetLogger(ctx)
+		logger.Debug("Processing listen event", "key", key, "index", index)
+
+		data.Data[item] = payload
+		data.Data[at] = index
+
+		return wf.runTasks(ctx, data, output)
+	}, nil
+}
+
+// ListenExtensions are the parts of the listen task that aren't in the SDK's
+// model, so are read from the raw workflow definition
+type ListenExtensions struct {
+	Foreach *ListenForeach `json:"foreach,omitempty"`
+	To      struct {
+		// Complete once this many events are received
+		Amount int `json:"amount,omitempty"`
+	} `json:"to"`
+}
+
+// Find all the listen tasks with extensions in the raw workflow definition,
+// keyed by the task key
+func findListenExtensions(doc any) (map[string]*ListenExtensions, error) {
+	found := make(map[string]*ListenExtensions)
+	err := walkTaskDefinitions(doc, func(key string, def any) error {
+		ext, err := getListenExtensions(def)
+		if err != nil {
+			return fmt.Errorf("%w: %s", err, key)
+		}
+		if ext == nil {
+			return nil
+		}
+		if _, exists := found[key]; exists {
+			return fmt.Errorf("%w: listen tasks using foreach or amount must have unique keys: %s", ErrDuplicateKey, key)
+		}
+		found[key] = ext
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return found, nil
+}
+
+func getListenExtensions(def any) (*ListenExtensions, error) {
+	d, ok := def.(map[string]any)
+	if !ok {
+		return nil, nil
+	}
+	listen, ok := d["listen"].(map[string]any)
+	if !ok {
+		return nil, nil
+	}
+
+	// Convert via JSON so the foreach task list is parsed by the SDK
+	b, err := json.Marshal(listen)
+	if err != nil {
+		return nil, fmt.Errorf("error converting listen task to json: %w", err)
+	}
+	var ext ListenExtensions
+	if err := json.Unmarshal(b, &ext); err != nil {
+		return nil, fmt.Errorf("error parsing listen task: %w", err)
+	}
+
+	if ext.Foreach == nil && ext.To.Amount == 0 {
+		return nil, nil
+	}
+
+	return &ext, nil
+}
@@ -0,0 +1,424 @@
+/*
+ * Copyright 2025 Simon Emms <simon@simonemms.com>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package workflow
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/serverlessworkflow/sdk-go/v3/model"
+	"go.temporal.io/sdk/activity"
+	"go.temporal.io/sdk/converter"
+)
+
+func TestConvertQueryData(t *testing.T) {
+	tests := []struct {
+		name        string
+		value       any
+		contentType string
+		expected    any
+		expectErr   bool
+	}{
+		{
+			name:        "json",
+			value:       `{"name": "alice", "age": 30}`,
+			contentType: "application/json",
+			expected:    &HTTPData{"name": "alice", "age": json.Number("30")},
+		},
+		{
+			name:        "yaml isn't json",
+			value:       "name: alice",
+			contentType: "application/json",
+			expectErr:   true,
+		},
+		{
+			name:        "yaml",
+			value:       "name: alice\nage: 30",
+			contentType: "application/yaml",
+			expected:    &HTTPData{"name": "alice", "age": 30},
+		},
+		{
+			name:        "legacy yaml",
+			value:       "name: alice",
+			contentType: "application/x-yaml",
+			expected:    &HTTPData{"name": "alice"},
+		},
+		{
+			name:        "text yaml",
+			value:       "name: alice",
+			contentType: "text/yaml",
+			expected:    &HTTPData{"name": "alice"},
+		},
+		{
+			name:        "text",
+			value:       "name: alice",
+			contentType: "text/plain",
+			expected:    "name: alice",
+		},
+		{
+			name:     "no content type",
+			value:    map[string]any{"name": "alice"},
+			expected: map[string]any{"name": "alice"},
+		},
+		{
+			name:        "json must be a string",
+			value:       map[string]any{"name": "alice"},
+			contentType: "application/json",
+			expectErr:   true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := convertQueryData(test.value, test.contentType)
+			if test.expectErr {
+				if err == nil {
+					t.Fatalf("expected an error, got %v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, test.expected) {
+				t.Errorf("expected %#v, got %#v", test.expected, got)
+			}
+		})
+	}
+}
+
+func TestListenConflictingStrategies(t *testing.T) {
+	// The SDK's parser rejects more than one of all, any and one
+	file := writeTestWorkflow(t, `
+document:
+  dsl: 1.0.0
+  namespace: test
+  name: listen
+  version: 0.0.1
+do:
+  - wait:
+      listen:
+        to:
+          all:
+            - with:
+                id: approve
+                type: signal
+          any:
+            - with:
+                id: reject
+                type: signal
+`)
+	if _, err := LoadFromFile(file, "TSW_"); err == nil {
+		t.Error("expected all and any to fail to load")
+	}
+
+	// The parser treats until as part of any, so it's left to the workflow
+	w := loadTestWorkflow(t, `
+document:
+  dsl: 1.0.0
+  namespace: test
+  name: listen
+  version: 0.0.1
+do:
+  - wait:
+      listen:
+        to:
+          any:
+            - with:
+                id: approve
+                type: signal
+          until: ${ .approved }
+`)
+
+	_, err := w.BuildWorkflows()
+	if !errors.Is(err, ErrMultipleListenStrategies) {
+		t.Fatalf("expected %v, got %v", ErrMultipleListenStrategies, err)
+	}
+
+	// Documents built outside of the parser are checked too
+	err = validateListenStrategy(&model.EventConsumptionStrategy{
+		All: []*model.EventFilter{{With: &model.EventProperties{ID: "approve", Type: "signal"}}},
+		One: &model.EventFilter{With: &model.EventProperties{ID: "reject", Type: "signal"}},
+	})
+	if !errors.Is(err, ErrMultipleListenStrategies) {
+		t.Fatalf("expected %v, got %v", ErrMultipleListenStrategies, err)
+	}
+}
+
+func TestListenQueryWhileParked(t *testing.T) {
+	w := loadTestWorkflow(t, `
+document:
+  dsl: 1.0.0
+  namespace: test
+  name: listen
+  version: 0.0.1
+do:
+  - queryState:
+      listen:
+        to:
+          one:
+            with:
+              id: get_state
+              type: query
+              datacontenttype: application/yaml
+              data: |
+                status: {{ .status | default "not started" }}
+  - setStatus:
+      set:
+        status: awaiting approval
+  - approve:
+      listen:
+        to:
+          one:
+            with:
+              id: approve
+              type: signal
+`)
+
+	env := newTestEnvironment(w)
+
+	var state HTTPData
+	env.RegisterDelayedCallback(func() {
+		res, err := env.QueryWorkflow("get_state")
+		if err != nil {
+			t.Errorf("error querying parked workflow: %v", err)
+		} else if err := res.Get(&state); err != nil {
+			t.Errorf("error getting query result: %v", err)
+		}
+
+		env.SignalWorkflow("approve", nil)
+	}, time.Hour)
+
+	if _, err := runTestWorkflowInEnvironment(t, env, buildTestWorkflow(t, w, "listen"), HTTPData{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if state["status"] != "awaiting approval" {
+		t.Errorf("expected the status while parked, got %#v", state)
+	}
+}
+
+func TestListenForeachActivityTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	t.Cleanup(server.Close)
+
+	w := loadTestWorkflow(t, fmt.Sprintf(`
+document:
+  dsl: 1.0.0
+  namespace: test
+  name: listen
+  version: 0.0.1
+do:
+  - before:
+      call: http
+      with:
+        method: get
+        endpoint: %[1]s
+  - measure:
+      listen:
+        to:
+          one:
+            with:
+              id: measure
+              type: signal
+        foreach:
+          activityTimeout: 5m
+          do:
+            - labelled:
+                metadata:
+                  labels:
+                    team: vitals
+                call: http
+                with:
+                  method: get
+                  endpoint: %[1]s
+            - ownTimeout:
+                timeout:
+                  after:
+                    seconds: 10
+                call: http
+                with:
+                  method: get
+                  endpoint: %[1]s
+`, server.URL), WithActivityOptions(ActivityOptionsConfig{
+		"CallHTTP": {StartToCloseTimeout: time.Minute},
+	}))
+
+	env := newTestEnvironment(w)
+
+	timeouts := map[string]time.Duration{}
+	env.SetOnActivityStartedListener(func(info *activity.Info, ctx context.Context, args converter.EncodedValues) {
+		timeouts[info.ActivityType.Name] = info.StartToCloseTimeout
+	})
+	env.RegisterDelayedCallback(func() {
+		env.SignalWorkflow("measure", nil)
+	}, time.Second)
+
+	if _, err := runTestWorkflowInEnvironment(t, env, buildTestWorkflow(t, w, "listen"), HTTPData{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// The foreach's timeout wins over the task type's, but not the task's own
+	expected := map[string]time.Duration{
+		CallHTTPActivityName("before"):     time.Minute,
+		CallHTTPActivityName("labelled"):   5 * time.Minute,
+		CallHTTPActivityName("ownTimeout"): 10 * time.Second,
+	}
+	if !reflect.DeepEqual(timeouts, expected) {
+		t.Errorf("expected %v, got %v", expected, timeouts)
+	}
+}
+
+func TestListenSignalQuorum(t *testing.T) {
+	tests := []struct {
+		name     string
+		signals  []string
+		err      string
+		expected []string
+	}{
+		{
+			name:     "two of three signals",
+			signals:  []string{"third", "first"},
+			expected: []string{"after"},
+		},
+		{
+			name:     "one of three signals",
+			signals:  []string{"third"},
+			err:      "signal not received within timeout",
+			expected: []string{},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			recorded := recordTasks(t)
+
+			w := loadTestWorkflow(t, `
+document:
+  dsl: 1.0.0
+  namespace: test
+  name: listen
+  version: 0.0.1
+do:
+  - approve:
+      listen:
+        to:
+          amount: 2
+          all:
+            - with:
+                id: first
+                type: signal
+                timeout: 1m
+            - with:
+                id: second
+                type: signal
+                timeout: 1m
+            - with:
+                id: third
+                type: signal
+  - after:
+      call: record
+`)
+
+			env := newTestEnvironment(w)
+			env.RegisterDelayedCallback(func() {
+				for _, signal := range test.signals {
+					env.SignalWorkflow(signal, nil)
+				}
+			}, 10*time.Second)
+
+			_, err := runTestWorkflowInEnvironment(t, env, buildTestWorkflow(t, w, "listen"), HTTPData{})
+			if test.err == "" && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if test.err != "" && (err == nil || !strings.Contains(err.Error(), test.err)) {
+				t.Fatalf("expected error containing %q, got %v", test.err, err)
+			}
+
+			if !slices.Equal(*recorded, test.expected) {
+				t.Errorf("expected %v, got %v", test.expected, *recorded)
+			}
+		})
+	}
+}
+
+func TestListenSignalTimeout(t *testing.T) {
+	tests := []struct {
+		name    string
+		timeout string
+		err     string
+	}{
+		{
+			name:    "zero timeout waits forever",
+			timeout: "0s",
+		},
+		{
+			name:    "timeout before the signal",
+			timeout: "1h",
+			err:     "signal not received within timeout",
+		},
+		{
+			name:    "negative timeout is rejected",
+			timeout: "-1h",
+			err:     ErrNegativeDuration.Error(),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			w := loadTestWorkflow(t, `
+document:
+  dsl: 1.0.0
+  namespace: test
+  name: listen
+  version: 0.0.1
+do:
+  - approve:
+      listen:
+        to:
+          one:
+            with:
+              id: approve
+              type: signal
+              timeout: `+test.timeout+`
+`)
+
+			env := newTestEnvironment(w)
+			// Long after any default timeout
+			env.RegisterDelayedCallback(func() {
+				env.SignalWorkflow("approve", nil)
+			}, 30*24*time.Hour)
+
+			_, err := runTestWorkflowInEnvironment(t, env, buildTestWorkflow(t, w, "listen"), HTTPData{})
+			if test.err == "" && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if test.err != "" && (err == nil || !strings.Contains(err.Error(), test.err)) {
+				t.Fatalf("expected error containing %q, got %v", test.err, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,58 @@
+/*
+ * Copyright 2025 Simon Emms <simon@simonemms.com>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package workflow
+
+import (
+	"fmt"
+
+	"github.com/serverlessworkflow/sdk-go/v3/model"
+	"go.temporal.io/sdk/workflow"
+)
+
+// The function called by a no-op task, eg "call: noop"
+const noopFunction = "noop"
+
+func isNoopTask(task *model.TaskItem) bool {
+	fn := task.AsCallFunctionTask()
+	return fn != nil && fn.Call == noopFunction
+}
+
+// A no-op task does nothing, but is in the plan like any other task. This is
+// for stubbing a step or as a target to move to. If it has a message, it's
+// interpolated and logged.
+func noopTaskImpl(task *model.CallFunction, key string) (TemporalWorkflowFunc, error) {
+	var message string
+	if m, ok := task.With["message"]; ok {
+		if message, ok = m.(string); !ok {
+			return nil, fmt.Errorf("%w: %s.with.message", ErrNotString, key)
+		}
+	}
+
+	return func(ctx workflow.Context, data *Variables, output map[string]OutputType) error {
+		if message == "" {
+			return nil
+		}
+
+		msg, err := ParseVariables(message, data)
+		if err != nil {
+			return WithExpressionContext(err, key, "with.message")
+		}
+		workflow.GetLogger(ctx).Info(msg, "task", key)
+
+		return nil
+	}, nil
+}
@@ -18,6 +18,9 @@ package workflow
 
 import (
 	"fmt"
+	"maps"
+	"regexp"
+	"slices"
 	"strconv"
 
 	"github.com/serverlessworkflow/sdk-go/v3/model"
@@ -27,18 +30,34 @@ import (
 // Wrap all set values in a SideEffect to allow for generated values
 // to be safely used. This avoid non-deterministic errors, which are a
 // pain in the arse in Temporalland
-func setTaskValue(ctx workflow.Context, input string, data *Variables) (string, error) {
+//
+// A jq expression, eg "${ .a + 1 }", keeps the type of its result. Anything
+// else is a template and returns a string.
+func setTaskValue(ctx workflow.Context, key, input string, data *Variables) (any, error) {
 	logger := workflow.GetLogger(ctx)
-	var str string
+	var value any
 	err := workflow.SideEffect(ctx, func(ctx workflow.Context) any {
-		return MustParseVariables(input, data)
-	}).Get(&str)
+		if isJQExpression(input) {
+			v, err := EvaluateJQ(input, key, data)
+			if err != nil {
+				panic(WithExpressionContext(err, "", key))
+			}
+			return v
+		}
+
+		v, err := ParseVariables(input, data)
+		if err != nil {
+			// Give the panic enough context to find the broken expression
+			panic(WithExpressionContext(err, "", key))
+		}
+		return v
+	}).Get(&value)
 	if err != nil {
 		logger.Error("Unable to generate side effect value", "error", err)
-		return "", fmt.Errorf("unable to generate side effect value: %w", err)
+		return nil, fmt.Errorf("unable to generate side effect value: %w", err)
 	}
 
-	return str, nil
+	return value, nil
 }
 
 func setTaskInterpolate(ctx workflow.Context, keyID, input any, data *Variables) (outputValue any, err error) {
@@ -50,8 +69,11 @@ func setTaskInterpolate(ctx workflow.Context, keyID, input any, data *Variables)
 		// Create a new object
 		obj := make(map[string]any)
 
-		// Iterate over each item
-		for i, item := range v {
+		// Iterate over each item in order, so side effects are replayed
+		// in the same sequence
+		for _, i := range slices.Sorted(maps.Keys(v)) {
+			item := v[i]
+
 			// Interpolate the object key
 			var key any
 			var keyStr string
@@ -93,7 +115,7 @@ func setTaskInterpolate(ctx workflow.Context, keyID, input any, data *Variables)
 		outputValue = arr
 	case string:
 		logger.Debug("Parsing as JSON string", "key", keyID)
-		outputValue, err = setTaskValue(ctx, v, data)
+		outputValue, err = setTaskValue(ctx, fmt.Sprint(keyID), v, data)
 	default:
 		logger.Debug("Maintaining JSON type", "key", keyID)
 		outputValue = v
@@ -105,9 +127,101 @@ func setTaskInterpolate(ctx workflow.Context, keyID, input any, data *Variables)
 	return outputValue, err
 }
 
+// Matches a reference to a variable, eg "{{ .key }}" or "${ .key + 1 }". A
+// field of another value, eg ".user.key", isn't a reference, as the dot
+// follows a name, index or optional.
+func setTaskReferencePattern(key string) *regexp.Regexp {
+	return regexp.MustCompile(`(^|[^\w\])?])\.` + regexp.QuoteMeta(key) + `(\W|$)`)
+}
+
+// Matches the key anywhere in a path, including as a field of another value.
+// This is how references were found before setTaskReferenceChange.
+func setTaskLegacyReferencePattern(key string) *regexp.Regexp {
+	return regexp.MustCompile(`\.` + regexp.QuoteMeta(key) + `(\W|$)`)
+}
+
+// Returns true if the value, or any key or item in it, matches the pattern
+func setTaskReferences(value any, pattern *regexp.Regexp) bool {
+	switch v := value.(type) {
+	case map[string]any:
+		for k, item := range v {
+			if setTaskReferences(k, pattern) || setTaskReferences(item, pattern) {
+				return true
+			}
+		}
+	case []any:
+		for _, item := range v {
+			if setTaskReferences(item, pattern) {
+				return true
+			}
+		}
+	case string:
+		return pattern.MatchString(v)
+	}
+	return false
+}
+
+// Orders the keys so a value referencing another key in the same task is set
+// after it. Otherwise, keys are sorted so the order is deterministic. Circular
+// references are set in sorted order.
+func setTaskOrder(set map[string]any, pattern func(key string) *regexp.Regexp) []string {
+	remaining := slices.Sorted(maps.Keys(set))
+	order := make([]string, 0, len(remaining))
+
+	// Find the references up front, so each key's pattern is compiled once
+	references := make(map[string][]string, len(remaining))
+	for _, dep := range remaining {
+		p := pattern(dep)
+		for _, key := range remaining {
+			if key != dep && setTaskReferences(set[key], p) {
+				references[key] = append(references[key], dep)
+			}
+		}
+	}
+
+	for len(remaining) > 0 {
+		next := slices.IndexFunc(remaining, func(key string) bool {
+			return !slices.ContainsFunc(references[key], func(dep string) bool {
+				return slices.Contains(remaining, dep)
+			})
+		})
+		if next == -1 {
+			// Circular reference
+			next = 0
+		}
+
+		order = append(order, remaining[next])
+		remaining = slices.Delete(remaining, next, next+1)
+	}
+
+	return order
+}
+
+// Versions the tighter reference matching, so runs started before it replay
+// setting the keys in the same order
+const setTaskReferenceChange = "set-task-references"
+
 func setTaskImpl(task *model.SetTask) TemporalWorkflowFunc {
+	order := setTaskOrder(task.Set, setTaskReferencePattern)
+	legacyOrder := setTaskOrder(task.Set, setTaskLegacyReferencePattern)
+
 	return func(ctx workflow.Context, data *Variables, output map[string]OutputType) error {
-		for key, value := range task.Set {
+		keys := order
+		// Only versioned if the order has changed, as it's the same otherwise
+		if !slices.Equal(order, legacyOrder) &&
+			workflow.GetVersion(ctx, setTaskReferenceChange, workflow.DefaultVersion, 1) == workflow.DefaultVersion {
+			keys = legacyOrder
+		}
+
+		for _, key := range keys {
+			value := task.Set[key]
+			if value == nil {
+				// A null value unsets the variable
+				workflow.GetLogger(ctx).Debug("Unsetting variable", "key", key)
+				delete(data.Data, key)
+				continue
+			}
+
 			var err error
 
 			value, err = setTaskInterpolate(ctx, key, value, data)
@@ -115,6 +229,7 @@ func setTaskImpl(task *model.SetTask) TemporalWorkflowFunc {
 				return err
 			}
 
+			// Set before the next key so it can reference this value
 			data.Data[key] = value
 		}
 
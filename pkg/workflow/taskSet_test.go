@@ -0,0 +1,119 @@
+/*
+ * Copyright 2025 Simon Emms <simon@simonemms.com>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package workflow
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestSetTaskOrder(t *testing.T) {
+	tests := []struct {
+		name     string
+		set      map[string]any
+		expected []string
+	}{
+		{
+			name:     "sorted",
+			set:      map[string]any{"b": 1, "c": 2, "a": 3},
+			expected: []string{"a", "b", "c"},
+		},
+		{
+			name: "dependencies",
+			set: map[string]any{
+				"greeting":  "Hello {{ .fullName }}",
+				"fullName":  `${ .firstName + " " + .lastName }`,
+				"firstName": "Ada",
+				"lastName":  "Lovelace",
+			},
+			expected: []string{"firstName", "lastName", "fullName", "greeting"},
+		},
+		{
+			name: "nested",
+			set: map[string]any{
+				"a": map[string]any{"value": []any{"${ .b }"}},
+				"b": 1,
+			},
+			expected: []string{"b", "a"},
+		},
+		{
+			name:     "prefix isn't a reference",
+			set:      map[string]any{"a": "${ .bb }", "b": 1},
+			expected: []string{"a", "b"},
+		},
+		{
+			name:     "field of another value isn't a reference",
+			set:      map[string]any{"a": "${ .user.b }", "b": 1, "c": "{{ .items[0].b }}"},
+			expected: []string{"a", "b", "c"},
+		},
+		{
+			name:     "root reference",
+			set:      map[string]any{"a": "{{ $.b }}", "b": "${.c}", "c": 1},
+			expected: []string{"c", "b", "a"},
+		},
+		{
+			name:     "circular",
+			set:      map[string]any{"a": "${ .b }", "b": "${ .a }"},
+			expected: []string{"a", "b"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := setTaskOrder(test.set, setTaskReferencePattern); !slices.Equal(got, test.expected) {
+				t.Errorf("expected %v, got %v", test.expected, got)
+			}
+		})
+	}
+}
+
+func TestSetDependentValues(t *testing.T) {
+	vars := captureVars(t)
+
+	w := loadTestWorkflow(t, `
+document:
+  dsl: 1.0.0
+  namespace: test
+  name: set
+  version: 0.0.1
+do:
+  - names:
+      set:
+        greeting: Hello {{ .fullName }}
+        fullName: ${ .firstName + " " + .lastName }
+        firstName: Ada
+        lastName: Lovelace
+  - capture:
+      call: capture
+`)
+
+	if _, err := runTestWorkflow(t, w, buildTestWorkflow(t, w, "set"), HTTPData{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := map[string]any{
+		"firstName": "Ada",
+		"lastName":  "Lovelace",
+		"fullName":  "Ada Lovelace",
+		"greeting":  "Hello Ada Lovelace",
+	}
+	for key, value := range expected {
+		if (*vars)[key] != value {
+			t.Errorf("expected %s to be %q, got %#v", key, value, (*vars)[key])
+		}
+	}
+}
@@ -0,0 +1,93 @@
+/*
+ * Copyright 2025 Simon Emms <simon@simonemms.com>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package workflow
+
+import "go.temporal.io/sdk/workflow"
+
+// TasksKey is the variable with the status of each task that's been reached,
+// eg ${ .["$tasks"].chargeCard.status == "faulted" }
+const TasksKey = "$tasks"
+
+// TaskStatusQueryName is the query that returns the status of each task
+const TaskStatusQueryName = "_tsw_tasks"
+
+// TaskStatus is where a task is in its lifecycle. Tasks are pending until
+// they're reached, then running until they've completed or faulted. A task
+// whose if is false is skipped instead.
+type TaskStatus string
+
+const (
+	TaskStatusCompleted TaskStatus = "completed"
+	TaskStatusFaulted   TaskStatus = "faulted"
+	TaskStatusPending   TaskStatus = "pending"
+	TaskStatusRunning   TaskStatus = "running"
+	TaskStatusSkipped   TaskStatus = "skipped"
+)
+
+// Sets up the $tasks variable, registering the query that returns it. This is
+// done before any task is run so the branches of a fork, which copy the
+// variables, share it.
+func initTaskStatuses(ctx workflow.Context, vars *Variables) error {
+	vars.Data[TasksKey] = map[string]any{}
+
+	return workflow.SetQueryHandler(ctx, TaskStatusQueryName, func() (map[string]any, error) {
+		tasks, _ := vars.Data[TasksKey].(map[string]any)
+		return tasks, nil
+	})
+}
+
+// Marks the tasks as pending before any of them are run. A group of tasks
+// that's run again, such as a retried do, starts from pending each time.
+func pendingTaskStatuses(vars *Variables, tasks []TemporalWorkflowTask) {
+	for _, task := range tasks {
+		setTaskStatus(vars, task.Key, TaskStatusPending)
+	}
+}
+
+// Sets the task's status in the $tasks variable, so later tasks can depend on
+// how it went. Tasks are keyed by name, so a nested task with the same name as
+// an earlier one replaces it.
+func setTaskStatus(vars *Variables, key string, status TaskStatus) {
+	tasks, ok := vars.Data[TasksKey].(map[string]any)
+	if !ok {
+		tasks = map[string]any{}
+		vars.Data[TasksKey] = tasks
+	}
+
+	// Stored as plain types so jq can read them
+	tasks[key] = map[string]any{
+		"status": string(status),
+	}
+}
+
+// Gets the task's status, if it's been reached
+func getTaskStatus(vars *Variables, key string) TaskStatus {
+	tasks, _ := vars.Data[TasksKey].(map[string]any)
+	task, _ := tasks[key].(map[string]any)
+	status, _ := task["status"].(string)
+
+	return TaskStatus(status)
+}
+
+// Marks a task that's returned without error as completed. A task that's
+// handled its own failure, such as with continueOnError, has already marked
+// itself as faulted so is left alone.
+func completeTaskStatus(vars *Variables, key string) {
+	if getTaskStatus(vars, key) == TaskStatusRunning {
+		setTaskStatus(vars, key, TaskStatusCompleted)
+	}
+}
@@ -0,0 +1,128 @@
+/*
+ * Copyright 2025 Simon Emms <simon@simonemms.com>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package workflow
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/serverlessworkflow/sdk-go/v3/model"
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/workflow"
+)
+
+// The default variable name for a caught error
+const defaultCatchAs = "error"
+
+// Converts a caught error to the Serverless Workflow error format. Any details
+// on a Temporal application error, such as a call http's status and body, are
+// kept so the catch can use them.
+func caughtError(err error) HTTPData {
+	caught := HTTPData{
+		"type":   "runtime",
+		"title":  err.Error(),
+		"detail": err.Error(),
+	}
+
+	var appErr *temporal.ApplicationError
+	if errors.As(err, &appErr) {
+		caught["type"] = appErr.Type()
+		caught["title"] = appErr.Message()
+
+		var details HTTPData
+		if appErr.HasDetails() && appErr.Details(&details) == nil {
+			caught["details"] = details
+			if status, ok := details["status"]; ok {
+				caught["status"] = status
+			}
+		}
+	}
+
+	return caught
+}
+
+// A try task runs its tasks inline. If one fails, the error is set in the
+// catch's variable and, if it's caught, the catch's tasks are run.
+func tryTaskImpl(try *model.TryTask, task *model.TaskItem, workflowInst *Workflow) (TemporalWorkflowFunc, error) {
+	if try.Catch == nil {
+		try.Catch = &model.TryTaskCatch{}
+	}
+	if try.Catch.Retry != nil {
+		return nil, fmt.Errorf("%w: catch.retry in task %s", ErrUnsupportedTryCatch, task.Key)
+	}
+	if try.Catch.Errors.With != nil {
+		return nil, fmt.Errorf("%w: catch.errors in task %s - use catch.when", ErrUnsupportedTryCatch, task.Key)
+	}
+
+	tryWorkflows, err := workflowInst.workflowBuilder(try.Try, task.Key, true)
+	if err != nil {
+		return nil, fmt.Errorf("error building try tasks: %w", err)
+	}
+	tryWf := tryWorkflows[len(tryWorkflows)-1]
+
+	var catchWf *TemporalWorkflow
+	if try.Catch.Do != nil {
+		catchWorkflows, err := workflowInst.workflowBuilder(try.Catch.Do, task.Key, true)
+		if err != nil {
+			return nil, fmt.Errorf("error building catch tasks: %w", err)
+		}
+		catchWf = catchWorkflows[len(catchWorkflows)-1]
+	}
+
+	as := try.Catch.As
+	if as == "" {
+		as = defaultCatchAs
+	}
+
+	return func(ctx workflow.Context, data *Variables, output map[string]OutputType) error {
+		logger := workflow.GetLogger(ctx)
+
+		err := tryWf.runTasks(ctx, data, output)
+		if err == nil || ctx.Err() != nil {
+			// Cancellation isn't caught
+			return err
+		}
+
+		data.Data[as] = caughtError(err)
+		defer delete(data.Data, as)
+
+		if try.Catch.When != nil {
+			caught, condErr := evaluateCondition(try.Catch.When.String(), "catch.when", data)
+			if condErr != nil {
+				return WithExpressionContext(condErr, task.Key, "catch.when")
+			}
+			if !caught {
+				return err
+			}
+		}
+		if try.Catch.ExceptWhen != nil {
+			excepted, condErr := evaluateCondition(try.Catch.ExceptWhen.String(), "catch.exceptWhen", data)
+			if condErr != nil {
+				return WithExpressionContext(condErr, task.Key, "catch.exceptWhen")
+			}
+			if excepted {
+				return err
+			}
+		}
+
+		logger.Info("Caught error in try task", "task", task.Key, "error", err)
+		if catchWf == nil {
+			return nil
+		}
+		return catchWf.runTasks(ctx, data, output)
+	}, nil
+}
@@ -23,11 +23,45 @@ import (
 	"go.temporal.io/sdk/workflow"
 )
 
-func waitTaskImpl(task *model.WaitTask) TemporalWorkflowFunc {
+func waitTaskImpl(task *model.WaitTask, key string, workflowInst *Workflow) (TemporalWorkflowFunc, error) {
+	guard, err := taskGuard(key, task.Metadata, workflowInst.jqDefs)
+	if err != nil {
+		return nil, err
+	}
+
 	return func(ctx workflow.Context, data *Variables, output map[string]OutputType) error {
 		logger := workflow.GetLogger(ctx)
 
-		duration := ToDuration(task.Wait)
+		duration, err := ToDuration(task.Wait)
+		if err != nil {
+			return fmt.Errorf("error parsing wait duration: %w", err)
+		}
+		if duration < 0 {
+			return fmt.Errorf("%w: wait %s", ErrNegativeDuration, duration)
+		}
+		if duration == 0 {
+			logger.Debug("Wait duration is zero - continuing")
+			return nil
+		}
+
+		if guard != "" {
+			// Wait for the duration, unless the guard stops holding first
+			logger.Debug("Sleeping with guard", "duration", duration.String(), "guard", guard)
+
+			check := guardCheck(guard, key, data)
+			var guardErr error
+			if _, err := workflow.AwaitWithTimeout(ctx, duration, func() bool {
+				guardErr = check()
+				return guardErr != nil
+			}); err != nil {
+				return fmt.Errorf("error sleeping: %w", err)
+			}
+			if guardErr != nil {
+				logger.Warn("Guard no longer holds - wait abandoned", "error", guardErr)
+			}
+
+			return guardErr
+		}
 
 		logger.Debug("Sleeping", "duration", duration.String())
 
@@ -36,5 +70,5 @@ func waitTaskImpl(task *model.WaitTask) TemporalWorkflowFunc {
 		}
 
 		return nil
-	}
+	}, nil
 }
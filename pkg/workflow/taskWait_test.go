@@ -0,0 +1,103 @@
+/*
+ * Copyright 2025 Simon Emms <simon@simonemms.com>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package workflow
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"go.temporal.io/sdk/workflow"
+)
+
+func TestWaitDuration(t *testing.T) {
+	tests := []struct {
+		name     string
+		wait     string
+		err      error
+		timers   int
+		expected []string
+	}{
+		{
+			name:     "zero wait is a no-op",
+			wait:     "seconds: 0",
+			timers:   0,
+			expected: []string{"before", "after"},
+		},
+		{
+			name:     "positive wait sleeps",
+			wait:     "seconds: 5",
+			timers:   1,
+			expected: []string{"before", "after"},
+		},
+		{
+			name:     "negative wait is rejected",
+			wait:     "seconds: -5",
+			err:      ErrNegativeDuration,
+			timers:   0,
+			expected: []string{"before"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			recorded := recordTasks(t)
+
+			w := loadTestWorkflow(t, `
+document:
+  dsl: 1.0.0
+  namespace: test
+  name: wait
+  version: 0.0.1
+do:
+  - before:
+      call: record
+  - pause:
+      wait:
+        `+test.wait+`
+  - after:
+      call: record
+`)
+
+			env := newTestEnvironment(w)
+			// Only count the wait's timers
+			env.OnGetVersion(runDeadlineChange, workflow.DefaultVersion, 1).Return(workflow.DefaultVersion)
+
+			timers := 0
+			env.SetOnTimerScheduledListener(func(timerID string, duration time.Duration) {
+				timers++
+			})
+
+			_, err := runTestWorkflowInEnvironment(t, env, buildTestWorkflow(t, w, "wait"), HTTPData{})
+			if test.err == nil && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			// The error is serialised by Temporal, so can't be checked with errors.Is
+			if test.err != nil && (err == nil || !strings.Contains(err.Error(), test.err.Error())) {
+				t.Fatalf("expected error containing %q, got %v", test.err, err)
+			}
+
+			if timers != test.timers {
+				t.Errorf("expected %d timers, got %d", test.timers, timers)
+			}
+			if !slices.Equal(*recorded, test.expected) {
+				t.Errorf("expected %v, got %v", test.expected, *recorded)
+			}
+		})
+	}
+}
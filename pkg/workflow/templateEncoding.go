@@ -0,0 +1,73 @@
+/*
+ * Copyright 2025 Simon Emms <simon@simonemms.com>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package workflow
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"hash"
+)
+
+// Encoding and hashing template functions. These are pure functions, so are
+// safe to use anywhere in a workflow, unlike sprig's random and time functions.
+var encodingFuncs = map[string]any{
+	// Hex encodes a string, eg {{ hexenc "hello" }}
+	"hexenc": func(s string) string {
+		return hex.EncodeToString([]byte(s))
+	},
+	// Decodes a hex string, returning an empty string if it's not valid hex
+	"hexdec": func(s string) string {
+		b, err := hex.DecodeString(s)
+		if err != nil {
+			return ""
+		}
+		return string(b)
+	},
+	// URL-safe base64, without padding, as used in JWTs
+	"b64urlenc": func(s string) string {
+		return base64.RawURLEncoding.EncodeToString([]byte(s))
+	},
+	"b64urldec": func(s string) string {
+		b, err := base64.RawURLEncoding.DecodeString(s)
+		if err != nil {
+			return ""
+		}
+		return string(b)
+	},
+	// Hex-encoded HMACs, eg {{ hmacSha256 .secret .body }}
+	"hmacSha256": func(key, message string) string {
+		return hmacHex(sha256.New, key, message)
+	},
+	"hmacSha512": func(key, message string) string {
+		return hmacHex(sha512.New, key, message)
+	},
+	// Base64-encoded HMAC, as expected by some webhook signatures
+	"hmacSha256b64": func(key, message string) string {
+		mac := hmac.New(sha256.New, []byte(key))
+		mac.Write([]byte(message))
+		return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+	},
+}
+
+func hmacHex(h func() hash.Hash, key, message string) string {
+	mac := hmac.New(h, []byte(key))
+	mac.Write([]byte(message))
+	return hex.EncodeToString(mac.Sum(nil))
+}
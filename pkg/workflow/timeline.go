@@ -0,0 +1,106 @@
+/*
+ * Copyright 2025 Simon Emms <simon@simonemms.com>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package workflow
+
+import (
+	"time"
+
+	"go.temporal.io/sdk/workflow"
+)
+
+// TimelineKey is the output key and query name for the timeline of tasks
+const TimelineKey = "__timeline"
+
+type TimelineStatus string
+
+const (
+	TimelineStatusCompleted TimelineStatus = "completed"
+	TimelineStatusFailed    TimelineStatus = "failed"
+	TimelineStatusSkipped   TimelineStatus = "skipped"
+)
+
+// TimelineEntry is a single task execution. Entries are recorded when the task
+// finishes, so tasks inside a do, fork or try are listed before their parent.
+type TimelineEntry struct {
+	Key    string         `json:"key"`
+	Type   string         `json:"type"`
+	Start  time.Time      `json:"start"`
+	End    time.Time      `json:"end"`
+	Status TimelineStatus `json:"status"`
+	Error  string         `json:"error,omitempty"`
+}
+
+type timeline struct {
+	entries []TimelineEntry
+}
+
+type timelineKey struct{}
+
+// Sets the timeline on the context, so inline workflows record to it too
+func withTimeline(ctx workflow.Context, t *timeline) workflow.Context {
+	return workflow.WithValue(ctx, timelineKey{}, t)
+}
+
+// Records the task, if the timeline is enabled. The times come from
+// workflow.Now so are the same on replay.
+func recordTimeline(ctx workflow.Context, task TemporalWorkflowTask, start time.Time, status TimelineStatus, err error) {
+	t, ok := ctx.Value(timelineKey{}).(*timeline)
+	if !ok || t == nil {
+		return
+	}
+
+	entry := TimelineEntry{
+		Key:    task.Key,
+		Type:   task.Type,
+		Start:  start,
+		End:    workflow.Now(ctx),
+		Status: status,
+	}
+	if err != nil {
+		entry.Error = err.Error()
+	}
+
+	t.entries = append(t.entries, entry)
+}
+
+// Enables the timeline for the workflow, returning it with the __timeline
+// query registered so it can be read while the workflow's running
+func (t *TemporalWorkflow) enableTimeline(ctx workflow.Context) (workflow.Context, *timeline, error) {
+	tl := &timeline{
+		entries: make([]TimelineEntry, 0),
+	}
+
+	if err := workflow.SetQueryHandler(ctx, TimelineKey, func() ([]TimelineEntry, error) {
+		return tl.entries, nil
+	}); err != nil {
+		return nil, nil, err
+	}
+
+	return withTimeline(ctx, tl), tl, nil
+}
+
+// Adds the timeline to the output, if it's enabled
+func addTimelineOutput(output map[string]OutputType, tl *timeline) {
+	if tl == nil {
+		return
+	}
+
+	output[TimelineKey] = OutputType{
+		Type: TimelineResultType,
+		Data: tl.entries,
+	}
+}
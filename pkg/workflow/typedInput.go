@@ -0,0 +1,56 @@
+/*
+ * Copyright 2025 Simon Emms <simon@simonemms.com>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package workflow
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/workflow"
+)
+
+// TypedWorkflow wraps the workflow so it can be registered with a typed input.
+// The input is converted to HTTPData, so the fields are set as variables using
+// their JSON names. Child workflows are always called with HTTPData, so only
+// the root workflow should be registered this way.
+func TypedWorkflow[T any](t *TemporalWorkflow) func(ctx workflow.Context, input T) (any, error) {
+	return func(ctx workflow.Context, input T) (any, error) {
+		data, err := typedInputData(input)
+		if err != nil {
+			workflow.GetLogger(ctx).Error("Error converting typed input", "error", err)
+			return nil, temporal.NewNonRetryableApplicationError(err.Error(), string(InputErr), err)
+		}
+
+		return t.Workflow(ctx, data)
+	}
+}
+
+// Converts a typed input to HTTPData via its JSON representation
+func typedInputData(input any) (HTTPData, error) {
+	b, err := json.Marshal(input)
+	if err != nil {
+		return nil, fmt.Errorf("error marshalling input: %w", err)
+	}
+
+	var data HTTPData
+	if err := decodeJSON(b, &data); err != nil {
+		return nil, fmt.Errorf("input must be a json object: %w", err)
+	}
+
+	return data, nil
+}
@@ -21,18 +21,150 @@ import (
 	"maps"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
+	"time"
 
+	"github.com/itchyny/gojq"
 	"github.com/serverlessworkflow/sdk-go/v3/model"
 	"github.com/serverlessworkflow/sdk-go/v3/parser"
+	"go.temporal.io/sdk/workflow"
+	"gopkg.in/yaml.v3"
 )
 
-type activities struct{}
+type activities struct {
+	emptyResponse     EmptyResponse
+	httpDebug         bool
+	httpDebugFile     string
+	httpDryRun        bool
+	httpErrorBodySize int
+	jqDefs            []*gojq.FuncDef
+}
 
 type Workflow struct {
-	data      []byte
-	envPrefix string
-	wf        *model.Workflow
+	activityOptions     ActivityOptionsConfig
+	allowUnsupported    []string
+	callHTTPExtensions  map[string]*CallHTTPExtensions
+	completeSignal      bool
+	data                []byte
+	definitions         *definitions
+	emptyResponse       EmptyResponse
+	envPrefix           string
+	foreachTimeout      time.Duration
+	httpDebugFile       string
+	httpDebugSize       int
+	httpActivityNames   []string
+	httpTaskQueues      HTTPTaskQueues
+	httpDryRun          bool
+	httpErrorBodySize   int
+	jqDefs              []*gojq.FuncDef
+	listenExtensions    map[string]*ListenExtensions
+	maxTasks            int
+	nameSuffix          string
+	onCancel            map[string]*model.TaskList
+	outputViews         OutputViews
+	priorityKey         int
+	rootOnCancel        *model.TaskList
+	tagsSearchAttribute string
+	timeline            bool
+	workflowIDPrefix    string
+	workflowRetry       *RetryConfig
+	wf                  *model.Workflow
+	workerIdentity      string
+}
+
+// Option configures the Workflow when it's loaded
+type Option func(*Workflow)
+
+// WithAllowUnsupported lets the named tasks, eg "switch", pass validation
+// even though they're not supported. This is for trying out tasks as they're
+// implemented.
+func WithAllowUnsupported(tasks []string) Option {
+	return func(w *Workflow) {
+		w.allowUnsupported = tasks
+	}
+}
+
+// WithCompleteSignal lets the workflows be completed early by sending the
+// complete signal, skipping the remaining tasks
+func WithCompleteSignal(enabled bool) Option {
+	return func(w *Workflow) {
+		w.completeSignal = enabled
+	}
+}
+
+// WithHTTPDebug records each HTTP request and response. The last size calls
+// are available in the workflow's state query and, if set, every call is
+// appended to the file. Secrets in the headers are redacted.
+func WithHTTPDebug(size int, file string) Option {
+	return func(w *Workflow) {
+		w.httpDebugSize = size
+		w.httpDebugFile = file
+	}
+}
+
+// WithEmptyResponse sets how an HTTP call's empty response body is returned
+func WithEmptyResponse(e EmptyResponse) Option {
+	return func(w *Workflow) {
+		w.emptyResponse = e
+	}
+}
+
+// WithHTTPDryRun logs the HTTP requests rather than sending them. This is for
+// local development only.
+func WithHTTPDryRun(dryRun bool) Option {
+	return func(w *Workflow) {
+		w.httpDryRun = dryRun
+	}
+}
+
+// WithNameSuffix adds a suffix to the registered workflow names, so the same
+// file can be registered for each environment in a shared namespace
+func WithNameSuffix(suffix string) Option {
+	return func(w *Workflow) {
+		w.nameSuffix = suffix
+	}
+}
+
+// WithPriorityKey sets the default priority key for the workflows and their
+// activities. Lower numbers are scheduled first. Any priority key set in the
+// workflow's metadata takes precedence.
+func WithPriorityKey(key int) Option {
+	return func(w *Workflow) {
+		w.priorityKey = key
+	}
+}
+
+// WithTagsSearchAttribute upserts the document tags to a keyword list search
+// attribute, which must already be registered in the namespace
+func WithTagsSearchAttribute(name string) Option {
+	return func(w *Workflow) {
+		w.tagsSearchAttribute = name
+	}
+}
+
+// WithTimeline records a timeline of the tasks run in the workflow's output
+// and the __timeline query
+func WithTimeline(enabled bool) Option {
+	return func(w *Workflow) {
+		w.timeline = enabled
+	}
+}
+
+// WithWorkerIdentity sets the worker identity returned by the state query, so
+// it's clear which worker build is running a workflow
+func WithWorkerIdentity(identity string) Option {
+	return func(w *Workflow) {
+		w.workerIdentity = identity
+	}
+}
+
+// WithWorkflowIDPrefix namespaces the generated workflow names and IDs, such
+// as for a tenant
+func WithWorkflowIDPrefix(prefix string) Option {
+	return func(w *Workflow) {
+		w.workflowIDPrefix = prefix
+	}
 }
 
 type OutputType struct {
@@ -44,6 +176,11 @@ type HTTPData map[string]any
 
 type Variables struct {
 	Data HTTPData `json:"data"`
+
+	// Not serialised so only available in the workflow
+	events    localEvents
+	httpDebug []*HTTPDebugRecord
+	jqDefs    []*gojq.FuncDef
 }
 
 func (a *Variables) AddData(d HTTPData) {
@@ -60,86 +197,189 @@ func (a *Variables) Clone() *Variables {
 	}
 
 	return &Variables{
-		Data: maps.Clone(a.Data),
+		Data:   maps.Clone(a.Data),
+		jqDefs: a.jqDefs,
 	}
 }
 
 func (w *Workflow) Activities() *activities {
-	return &activities{}
+	return &activities{
+		emptyResponse:     w.emptyResponse,
+		httpDebug:         w.httpDebugSize > 0,
+		httpDebugFile:     w.httpDebugFile,
+		httpDryRun:        w.httpDryRun,
+		httpErrorBodySize: w.httpErrorBodySize,
+		jqDefs:            w.jqDefs,
+	}
 }
 
+// CallHTTPActivityNames are the names each http call's activity is registered
+// as, in addition to the generic CallHTTP activity
+func (w *Workflow) CallHTTPActivityNames() []string {
+	return w.httpActivityNames
+}
+
+// WorkflowName is the name the document's workflow is registered as
 func (w *Workflow) WorkflowName() string {
-	return w.wf.Document.Name
+	return w.registeredName(w.wf.Document.Name)
 }
 
-// Validation of the schema is handled separately. This validates that there is
-// nothing used we've not implemented. This should reduce over time.
-func validateTaskSupported(task *model.TaskItem) error {
-	if doTask := task.AsDoTask(); doTask != nil {
-		// Do task - iterate through these
-		for _, t := range *doTask.Do {
-			if err := validateTaskSupported(t); err != nil {
-				return err
-			}
-		}
+// Adds any name suffix to a workflow name, eg "order-process-staging"
+func (w *Workflow) registeredName(name string) string {
+	if w.nameSuffix == "" {
+		return name
 	}
+	return name + "-" + w.nameSuffix
+}
 
-	if emit := task.AsEmitTask(); emit != nil {
-		return fmt.Errorf("%w: emit", ErrUnsupportedTask)
-	}
-	if forTask := task.AsForTask(); forTask != nil {
-		return fmt.Errorf("%w: for", ErrUnsupportedTask)
-	}
-	if grpc := task.AsCallGRPCTask(); grpc != nil {
-		return fmt.Errorf("%w: grpc", ErrUnsupportedTask)
-	}
-	if openapi := task.AsCallOpenAPITask(); openapi != nil {
-		return fmt.Errorf("%w: openapi", ErrUnsupportedTask)
-	}
-	if raise := task.AsRaiseTask(); raise != nil {
-		return fmt.Errorf("%w: raise", ErrUnsupportedTask)
-	}
-	if run := task.AsRunTask(); run != nil {
-		return fmt.Errorf("%w: run", ErrUnsupportedTask)
-	}
-	if switchTask := task.AsSwitchTask(); switchTask != nil {
-		return fmt.Errorf("%w: switch", ErrUnsupportedTask)
+func (w *Workflow) WorkflowIDPrefix() string {
+	return w.workflowIDPrefix
+}
+
+// PrefixWorkflowID namespaces a generated workflow ID, such as for a tenant
+func PrefixWorkflowID(prefix, id string) string {
+	if prefix == "" {
+		return id
 	}
-	if try := task.AsTryTask(); try != nil {
-		return fmt.Errorf("%w: try", ErrUnsupportedTask)
+	return fmt.Sprintf("%s_%s", prefix, id)
+}
+
+// Generates the child workflow name, including any workflow ID prefix
+func (w *Workflow) GenerateChildWorkflowName(prefix string, prefixes ...string) string {
+	name := w.registeredName(GenerateChildWorkflowName(prefix, prefixes...))
+	if w.workflowIDPrefix != "" {
+		name = fmt.Sprintf("%s_%s", w.workflowIDPrefix, name)
 	}
-	return nil
+
+	return name
+}
+
+// Generates the child workflow execution ID, including any workflow ID prefix
+func (w *Workflow) GenerateChildWorkflowID(ctx workflow.Context, key string, index int) string {
+	return GenerateChildWorkflowID(ctx, w.workflowIDPrefix, key, index)
 }
 
 func (w *Workflow) Validate() error {
+	if err := validateTaskNames(w.allowUnsupported); err != nil {
+		return fmt.Errorf("invalid allowed unsupported tasks: %w", err)
+	}
+
 	for _, task := range *w.wf.Do {
-		if err := validateTaskSupported(task); err != nil {
+		if err := validateTaskSupported(task, w.allowUnsupported); err != nil {
 			return err
 		}
 	}
 
+	onCancel := []*model.TaskList{w.rootOnCancel}
+	for _, tasks := range w.onCancel {
+		onCancel = append(onCancel, tasks)
+	}
+	for _, tasks := range onCancel {
+		if tasks == nil {
+			continue
+		}
+		for _, task := range *tasks {
+			if err := validateTaskSupported(task, w.allowUnsupported); err != nil {
+				return err
+			}
+		}
+	}
+
 	return nil
 }
 
-func LoadFromFile(file, envPrefix string) (*Workflow, error) {
+func LoadFromFile(file, envPrefix string, opts ...Option) (*Workflow, error) {
 	data, err := os.ReadFile(filepath.Clean(file))
 	if err != nil {
 		return nil, fmt.Errorf("error loading file: %w", err)
 	}
 
-	wf, err := parser.FromYAMLSource(data)
+	doc, err := loadRawDefinition(data, file)
+	if err != nil {
+		return nil, err
+	}
+
+	// Hashed before the extensions are removed, so changing them changes the
+	// hash too
+	defs, err := findDefinitions(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	// The functions are needed to check the other expressions
+	jqDefs, err := findJQDefs(doc)
+	if err != nil {
+		return nil, fmt.Errorf("error loading jq functions: %w", err)
+	}
+
+	// Read before the parser is given the document, as the headers expressions
+	// and custom methods are removed from it
+	callHTTPExtensions, err := findCallHTTPExtensions(doc, jqDefs)
+	if err != nil {
+		return nil, fmt.Errorf("error loading call http extensions: %w", err)
+	}
+	if err := stripCallHTTPExtensions(doc); err != nil {
+		return nil, err
+	}
+
+	// The parser is given the decoded document so any anchors and aliases are
+	// fully resolved first
+	resolved, err := yaml.Marshal(doc)
+	if err != nil {
+		return nil, fmt.Errorf("error resolving yaml: %w", err)
+	}
+
+	wf, err := parser.FromYAMLSource(resolved)
 	if err != nil {
 		return nil, fmt.Errorf("error loading yaml: %w", err)
 	}
 
 	// Only support dsl v1.0.0 - we may support later versions
-	if dsl := wf.Document.DSL; dsl != "1.0.0" {
+	if dsl := wf.Document.DSL; !slices.Contains(supportedDSLVersions, dsl) {
 		return nil, fmt.Errorf("%w: %s", ErrUnsupportedDSL, dsl)
 	}
 
-	return &Workflow{
-		data:      data,
-		envPrefix: strings.ToUpper(envPrefix),
-		wf:        wf,
-	}, nil
+	listenExtensions, err := findListenExtensions(doc)
+	if err != nil {
+		return nil, fmt.Errorf("error loading listen extensions: %w", err)
+	}
+
+	httpActivityNames, err := findCallHTTPActivityNames(doc)
+	if err != nil {
+		return nil, fmt.Errorf("error loading call http tasks: %w", err)
+	}
+
+	signingPolicies, err := findSigningPolicies(doc)
+	if err != nil {
+		return nil, fmt.Errorf("error loading signing policies: %w", err)
+	}
+	if err := resolveSigningPolicies(callHTTPExtensions, signingPolicies); err != nil {
+		return nil, err
+	}
+
+	rootOnCancel, onCancel, err := findOnCancel(doc)
+	if err != nil {
+		return nil, fmt.Errorf("error loading onCancel: %w", err)
+	}
+
+	w := &Workflow{
+		callHTTPExtensions: callHTTPExtensions,
+		data:               data,
+		definitions:        defs,
+		envPrefix:          strings.ToUpper(envPrefix),
+		httpActivityNames:  httpActivityNames,
+		httpErrorBodySize:  DefaultHTTPErrorBodySize,
+		jqDefs:             jqDefs,
+		listenExtensions:   listenExtensions,
+		maxTasks:           DefaultMaxTasks,
+		onCancel:           onCancel,
+		rootOnCancel:       rootOnCancel,
+		wf:                 wf,
+	}
+
+	for _, opt := range opts {
+		opt(w)
+	}
+
+	return w, nil
 }
@@ -18,8 +18,15 @@ package workflow
 
 import (
 	"bytes"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"maps"
+	"math/big"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
 	"strings"
 	"text/template"
 	"time"
@@ -28,50 +35,209 @@ import (
 	"github.com/itchyny/gojq"
 	"github.com/serverlessworkflow/sdk-go/v3/model"
 	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/workflow"
 	"gopkg.in/yaml.v3"
 )
 
 func CheckIfStatement(task *model.TaskBase, input *Variables) (toRun bool, err error) {
-	if task.If != nil {
-		var query *gojq.Query
+	if task.If == nil {
+		// No statement - continue with true
+		return true, nil
+	}
+
+	return evaluateCondition(task.If.String(), "if", input)
+}
 
-		expression := model.SanitizeExpr(task.If.String())
-		query, err = gojq.Parse(expression)
+// Evaluates a condition, such as an if, against the variables
+func evaluateCondition(expression, field string, input *Variables) (result bool, err error) {
+	var query *gojq.Code
+
+	// Any templates are interpolated first, then the result is run as jq
+	if strings.Contains(expression, "{{") {
+		expression, err = ParseVariables(expression, input)
 		if err != nil {
-			err = fmt.Errorf("unable to parse if statement as expression: %w", err)
-			return toRun, err
+			err = WithExpressionContext(err, "", field)
+			return result, temporal.NewNonRetryableApplicationError(
+				fmt.Sprintf("Error interpolating %s statement", field), string(IfStatementErr), err,
+			)
 		}
+	}
+
+	expression = model.SanitizeExpr(expression)
+	query, err = parseJQ(expression, field, input.jqDefs...)
+	if err != nil {
+		err = fmt.Errorf("unable to parse %s statement as expression: %w", field, err)
+		return result, err
+	}
 
-		// For some reason, GoJQ doesn't like HTTPData even though it's map[string]any 😕
-		data := make(map[string]any)
-		maps.Copy(data, input.Data)
+	iter := query.Run(jqInput(input))
+	for {
+		v, ok := iter.Next()
+		if !ok {
+			break
+		}
+		if err, ok = v.(error); ok {
+			// Any JQ error will be considered a non-retryable error
+			err = temporal.NewNonRetryableApplicationError(
+				fmt.Sprintf("Error parsing %s statement in JQ", field), string(IfStatementErr), &ExpressionError{
+					Field:      field,
+					Expression: expression,
+					Err:        err,
+				},
+			)
+			return result, err
+		}
 
-		iter := query.Run(data)
-		for {
-			v, ok := iter.Next()
-			if !ok {
-				break
-			}
-			if err, ok = v.(error); ok {
-				// Any JQ error will be considered a non-retryable error
-				err = temporal.NewNonRetryableApplicationError("Error parsing if statement in JQ", string(IfStatementErr), err)
-				return toRun, err
-			}
+		switch r := v.(type) {
+		case bool:
+			result = r
+		case string:
+			// Can resolve "TRUE" or "1"
+			result = strings.EqualFold(r, "TRUE") || r == "1"
+		}
+	}
+
+	return result, nil
+}
+
+// Parses a jq expression, giving the position of any error. Any function
+// definitions from the workflow are available in the expression.
+func parseJQ(expression, field string, defs ...*gojq.FuncDef) (*gojq.Code, error) {
+	query, err := gojq.Parse(expression)
+	if err != nil {
+		exprErr := &ExpressionError{
+			Field:      field,
+			Expression: expression,
+			Err:        err,
+		}
+		var parseErr *gojq.ParseError
+		if errors.As(err, &parseErr) {
+			exprErr.Line, exprErr.Column = offsetToPosition(expression, parseErr.Offset)
+		}
+		return nil, exprErr
+	}
+	query.FuncDefs = append(slices.Clone(defs), query.FuncDefs...)
+
+	code, err := gojq.Compile(query, jqFunctions...)
+	if err != nil {
+		return nil, &ExpressionError{
+			Field:      field,
+			Expression: expression,
+			Err:        err,
+		}
+	}
+
+	return code, nil
+}
+
+// Additional functions available in jq expressions
+var jqFunctions = []gojq.CompilerOption{
+	// Checks if the input is one of the values, eg .status | oneof(["a", "b"])
+	gojq.WithFunction("oneof", 1, 1, func(v any, args []any) any {
+		values, ok := args[0].([]any)
+		if !ok {
+			return fmt.Errorf("oneof cannot be applied to: %v", args[0])
+		}
+		return slices.ContainsFunc(values, func(value any) bool {
+			return valuesEqual(v, value)
+		})
+	}),
+}
+
+// Additional functions available in templates
+func templateFuncs() template.FuncMap {
+	funcs := sprig.FuncMap()
+	maps.Copy(funcs, encodingFuncs)
+	// Checks if the value is one of the values, eg {{ in .status "a" "b" }}
+	funcs["in"] = func(v any, values ...any) bool {
+		return slices.ContainsFunc(values, func(value any) bool {
+			return valuesEqual(v, value)
+		})
+	}
+	return funcs
+}
+
+// Compares two values, treating numbers of any type as equal if they're the
+// same value as JSON numbers are decoded as float64
+func valuesEqual(a, b any) bool {
+	if x, ok := toFloat(a); ok {
+		y, ok := toFloat(b)
+		return ok && x == y
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+func toFloat(v any) (float64, bool) {
+	switch n := v.(type) {
+	case int:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case float32:
+		return float64(n), true
+	case float64:
+		return n, true
+	case json.Number:
+		f, err := n.Float64()
+		return f, err == nil
+	case *big.Int:
+		f, _ := new(big.Float).SetInt(n).Float64()
+		return f, true
+	}
+	return 0, false
+}
+
+// For some reason, GoJQ doesn't like HTTPData even though it's map[string]any 😕
+func jqInput(input *Variables) map[string]any {
+	data := make(map[string]any)
+	maps.Copy(data, input.Data)
 
-			switch r := v.(type) {
-			case bool:
-				toRun = r
-			case string:
-				// Can resolve "TRUE" or "1"
-				toRun = strings.EqualFold(r, "TRUE") || r == "1"
+	return data
+}
+
+// Returns true if the whole string is a jq expression, eg "${ .a + 1 }"
+func isJQExpression(s string) bool {
+	s = strings.TrimSpace(s)
+	return strings.HasPrefix(s, "${") && strings.HasSuffix(s, "}")
+}
+
+// Runs a jq expression against the variables. A single result is returned
+// as-is, with multiple results returned as an array
+func EvaluateJQ(expression, field string, input *Variables) (any, error) {
+	expression = model.SanitizeExpr(expression)
+
+	query, err := parseJQ(expression, field, input.jqDefs...)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]any, 0)
+	iter := query.Run(jqInput(input))
+	for {
+		v, ok := iter.Next()
+		if !ok {
+			break
+		}
+		if err, ok := v.(error); ok {
+			return nil, &ExpressionError{
+				Field:      field,
+				Expression: expression,
+				Err:        err,
 			}
 		}
-	} else {
-		// No statement - continue with true
-		toRun = true
+		results = append(results, v)
 	}
 
-	return toRun, err
+	switch len(results) {
+	case 0:
+		return nil, nil
+	case 1:
+		return results[0], nil
+	default:
+		return results, nil
+	}
 }
 
 func GenerateChildWorkflowName(prefix string, prefixes ...string) string {
@@ -80,10 +246,34 @@ func GenerateChildWorkflowName(prefix string, prefixes ...string) string {
 	return fmt.Sprintf("workflow_%s", strings.Join(prefixes, "_"))
 }
 
+// Generates a deterministic execution ID for a child workflow. This is
+// built from the parent run ID, task key and branch index so it's stable
+// on replay and won't collide across parent runs. The child workflow name
+// is the type, not the execution ID.
+func GenerateChildWorkflowID(ctx workflow.Context, idPrefix, key string, index int) string {
+	info := workflow.GetInfo(ctx)
+
+	id := strings.Join([]string{info.WorkflowExecution.RunID, key, strconv.Itoa(index)}, "_")
+
+	return PrefixWorkflowID(idPrefix, id)
+}
+
 // Interpolate the given input. Unlike the interpolation in the SetTask, this
 // only works with the given data and should be used for getting data rather
 // than setting data - this may given non-deterministic errors
 func Interpolate(input any, data *Variables) (outputValue any, err error) {
+	return interpolate(input, data, "")
+}
+
+// Joins the path to the field being interpolated, used for error context
+func fieldPath(parent, key string) string {
+	if parent == "" {
+		return key
+	}
+	return parent + "." + key
+}
+
+func interpolate(input any, data *Variables, path string) (outputValue any, err error) {
 	switch v := input.(type) {
 	case map[string]any:
 		// Create a new object
@@ -94,7 +284,7 @@ func Interpolate(input any, data *Variables) (outputValue any, err error) {
 			// Interpolate the object key
 			var key any
 			var keyStr string
-			key, err = Interpolate(i, data)
+			key, err = interpolate(i, data, fieldPath(path, i))
 			if err != nil {
 				return outputValue, err
 			}
@@ -106,7 +296,7 @@ func Interpolate(input any, data *Variables) (outputValue any, err error) {
 			}
 
 			var o any
-			o, err = Interpolate(item, data)
+			o, err = interpolate(item, data, fieldPath(path, i))
 			if err != nil {
 				return outputValue, err
 			}
@@ -119,9 +309,9 @@ func Interpolate(input any, data *Variables) (outputValue any, err error) {
 		arr := make([]any, 0)
 
 		// Iterate over each item
-		for _, item := range v {
+		for i, item := range v {
 			var o any
-			o, err = Interpolate(item, data)
+			o, err = interpolate(item, data, fieldPath(path, strconv.Itoa(i)))
 			if err != nil {
 				return outputValue, err
 			}
@@ -131,6 +321,7 @@ func Interpolate(input any, data *Variables) (outputValue any, err error) {
 		outputValue = arr
 	case string:
 		outputValue, err = ParseVariables(v, data)
+		err = WithExpressionContext(err, "", path)
 	default:
 		outputValue = v
 	}
@@ -138,18 +329,52 @@ func Interpolate(input any, data *Variables) (outputValue any, err error) {
 	return outputValue, err
 }
 
+// Template errors are in the format "template: <name>:<line>[:<col>]: <msg>"
+var templateErrPosition = regexp.MustCompile(`^template: [^:]+:(\d+)(?::(\d+))?:`)
+
+// Build an ExpressionError from a template error, pulling out the line and
+// column if they're given
+func newTemplateExpressionError(input string, err error) *ExpressionError {
+	exprErr := &ExpressionError{
+		Expression: input,
+		Err:        err,
+	}
+
+	if m := templateErrPosition.FindStringSubmatch(err.Error()); m != nil {
+		exprErr.Line, _ = strconv.Atoi(m[1])
+		if m[2] != "" {
+			exprErr.Column, _ = strconv.Atoi(m[2])
+		}
+	}
+
+	return exprErr
+}
+
+// Converts a character offset into a one-indexed line and column
+func offsetToPosition(input string, offset int) (line, column int) {
+	if offset > len(input) {
+		offset = len(input)
+	}
+
+	before := input[:offset]
+	line = strings.Count(before, "\n") + 1
+	column = offset - strings.LastIndex(before, "\n")
+
+	return line, column
+}
+
 // Parses a string with variables
 func ParseVariables(input string, data *Variables) (string, error) {
 	t, err := template.New("values").
-		Funcs(sprig.FuncMap()).
+		Funcs(templateFuncs()).
 		Parse(input)
 	if err != nil {
-		return "", fmt.Errorf("error creating template instance: %w", err)
+		return "", fmt.Errorf("error creating template instance: %w", newTemplateExpressionError(input, err))
 	}
 
 	buf := new(bytes.Buffer)
 	if err := t.Execute(buf, data.Data); err != nil {
-		return "", fmt.Errorf("error executing template: %w", err)
+		return "", fmt.Errorf("error executing template: %w", newTemplateExpressionError(input, err))
 	}
 
 	return buf.String(), nil
@@ -173,11 +398,23 @@ func SlicesEqual[T comparable](s []T, v T) bool {
 	return true
 }
 
+func FromJSON(input any) (*HTTPData, error) {
+	if i, ok := input.(string); ok {
+		var data *HTTPData
+		if err := decodeJSON([]byte(i), &data); err != nil {
+			return nil, fmt.Errorf("error converting json: %w", err)
+		}
+		return data, nil
+	}
+
+	return nil, ErrNotString
+}
+
 func FromYAML(input any) (*HTTPData, error) {
 	if i, ok := input.(string); ok {
 		var data *HTTPData
 		if err := yaml.Unmarshal([]byte(i), &data); err != nil {
-			return nil, fmt.Errorf("error converting json: %w", err)
+			return nil, fmt.Errorf("error converting yaml: %w", err)
 		}
 		return data, nil
 	}
@@ -185,9 +422,58 @@ func FromYAML(input any) (*HTTPData, error) {
 	return nil, ErrNotString
 }
 
-// Converts the SW duration to a time Duration
-func ToDuration(v *model.Duration) time.Duration {
+// ISO-8601 durations, such as PT1H30M. Years and months are not supported as
+// their length varies.
+var iso8601DurationRegex = regexp.MustCompile(`^P(?:(\d+)W)?(?:(\d+)D)?(?:T(?:(\d+)H)?(?:(\d+)M)?(?:(\d+(?:\.\d+)?)S)?)?$`)
+
+// ParseAnyDuration parses either a Go duration (eg, 1h30m) or an ISO-8601
+// duration (eg, PT1H30M)
+func ParseAnyDuration(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+
+	if d, err := time.ParseDuration(s); err == nil {
+		return d, nil
+	}
+
+	m := iso8601DurationRegex.FindStringSubmatch(strings.ToUpper(s))
+	if m == nil || s == "P" || strings.HasSuffix(strings.ToUpper(s), "T") {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidDuration, s)
+	}
+
+	units := []time.Duration{time.Hour * 24 * 7, time.Hour * 24, time.Hour, time.Minute}
+	var duration time.Duration
+	for i, unit := range units {
+		if m[i+1] == "" {
+			continue
+		}
+		n, err := strconv.ParseInt(m[i+1], 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("%w: %q", ErrInvalidDuration, s)
+		}
+		duration += time.Duration(n) * unit
+	}
+	if m[5] != "" {
+		seconds, err := strconv.ParseFloat(m[5], 64)
+		if err != nil {
+			return 0, fmt.Errorf("%w: %q", ErrInvalidDuration, s)
+		}
+		duration += time.Duration(seconds * float64(time.Second))
+	}
+
+	return duration, nil
+}
+
+// Converts the SW duration to a time Duration. This is either the inline
+// object or a duration string, in Go or ISO-8601 format.
+func ToDuration(v *model.Duration) (time.Duration, error) {
+	if v == nil {
+		return 0, nil
+	}
+
 	inline := v.AsInline()
+	if inline == nil {
+		return ParseAnyDuration(v.AsExpression())
+	}
 
 	var duration time.Duration
 	duration += time.Millisecond * time.Duration(inline.Milliseconds)
@@ -196,5 +482,5 @@ func ToDuration(v *model.Duration) time.Duration {
 	duration += time.Hour * time.Duration(inline.Hours)
 	duration += (time.Hour * 24) * time.Duration(inline.Days)
 
-	return duration
+	return duration, nil
 }
@@ -0,0 +1,190 @@
+/*
+ * Copyright 2025 Simon Emms <simon@simonemms.com>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package workflow
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"go.temporal.io/sdk/testsuite"
+	"go.temporal.io/sdk/workflow"
+)
+
+func TestGenerateChildWorkflowID(t *testing.T) {
+	w := loadTestWorkflow(t, `
+document:
+  dsl: 1.0.0
+  namespace: test
+  name: child
+  version: 0.0.1
+do:
+  - wait:
+      wait:
+        seconds: 1
+`, WithWorkflowIDPrefix("tenant"))
+
+	// Generates the IDs either side of a timer, so the second set is built
+	// after the workflow has yielded, as it would be on replay
+	childIDs := func(ctx workflow.Context) ([]string, error) {
+		ids := []string{
+			w.GenerateChildWorkflowID(ctx, "fork", 0),
+			w.GenerateChildWorkflowID(ctx, "fork", 1),
+		}
+		if err := workflow.Sleep(ctx, time.Second); err != nil {
+			return nil, err
+		}
+		return append(ids,
+			w.GenerateChildWorkflowID(ctx, "fork", 0),
+			w.GenerateChildWorkflowID(ctx, "fork", 1),
+		), nil
+	}
+
+	// Each parent run is started as a child of the test workflow, as the test
+	// environment gives every child its own run ID
+	parents := func(ctx workflow.Context) ([][]string, error) {
+		var runs [][]string
+		for _, id := range []string{"parent-1", "parent-2"} {
+			cctx := workflow.WithChildOptions(ctx, workflow.ChildWorkflowOptions{WorkflowID: id})
+
+			var ids []string
+			if err := workflow.ExecuteChildWorkflow(cctx, "childIDs").Get(ctx, &ids); err != nil {
+				return nil, err
+			}
+			runs = append(runs, ids)
+		}
+		return runs, nil
+	}
+
+	run := func() [][]string {
+		t.Helper()
+
+		var s testsuite.WorkflowTestSuite
+		env := s.NewTestWorkflowEnvironment()
+		env.RegisterWorkflowWithOptions(childIDs, workflow.RegisterOptions{Name: "childIDs"})
+		env.RegisterWorkflowWithOptions(parents, workflow.RegisterOptions{Name: "parents"})
+		env.ExecuteWorkflow("parents")
+
+		if err := env.GetWorkflowError(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		var runs [][]string
+		if err := env.GetWorkflowResult(&runs); err != nil {
+			t.Fatalf("unexpected error getting result: %v", err)
+		}
+		return runs
+	}
+
+	runs := run()
+	if len(runs) != 2 {
+		t.Fatalf("expected 2 parent runs, got %d", len(runs))
+	}
+
+	expected := []string{
+		"tenant_parent-1_RunID_fork_0",
+		"tenant_parent-1_RunID_fork_1",
+		"tenant_parent-1_RunID_fork_0",
+		"tenant_parent-1_RunID_fork_1",
+	}
+	if !slices.Equal(runs[0], expected) {
+		t.Errorf("expected %v, got %v", expected, runs[0])
+	}
+
+	if runs[1][0] == runs[0][0] {
+		t.Errorf("expected a different parent run to generate a different ID, got %s", runs[1][0])
+	}
+
+	if again := run(); !slices.Equal(again[0], runs[0]) {
+		t.Errorf("expected the same run to generate %v, got %v", runs[0], again[0])
+	}
+}
+
+func TestIfMembership(t *testing.T) {
+	tests := []struct {
+		name      string
+		condition string
+		input     HTTPData
+		expected  []string
+	}{
+		{
+			name:      "oneof match",
+			condition: `${ .status | oneof(["cancelled", "completed"]) }`,
+			input:     HTTPData{"status": "completed"},
+			expected:  []string{"check"},
+		},
+		{
+			name:      "oneof no match",
+			condition: `${ .status | oneof(["cancelled", "completed"]) }`,
+			input:     HTTPData{"status": "pending"},
+			expected:  []string{},
+		},
+		{
+			name:      "oneof def match",
+			condition: `${ .status | isClosed }`,
+			input:     HTTPData{"status": "cancelled"},
+			expected:  []string{"check"},
+		},
+		{
+			name:      "oneof def no match",
+			condition: `${ .status | isClosed }`,
+			input:     HTTPData{"status": "pending"},
+			expected:  []string{},
+		},
+		{
+			name:      "in match",
+			condition: `${ "{{ in .errorCode 502 503 504 }}" }`,
+			input:     HTTPData{"errorCode": 503},
+			expected:  []string{"check"},
+		},
+		{
+			name:      "in no match",
+			condition: `${ "{{ in .errorCode 502 503 504 }}" }`,
+			input:     HTTPData{"errorCode": 404},
+			expected:  []string{},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			recorded := recordTasks(t)
+
+			w := loadTestWorkflow(t, `
+document:
+  dsl: 1.0.0
+  namespace: test
+  name: membership
+  version: 0.0.1
+use:
+  jqFunctions: |
+    def isClosed: oneof(["cancelled", "completed"]);
+do:
+  - check:
+      if: '`+test.condition+`'
+      call: record
+`)
+
+			if _, err := runTestWorkflow(t, w, buildTestWorkflow(t, w, "membership"), test.input); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if !slices.Equal(*recorded, test.expected) {
+				t.Errorf("expected %v, got %v", test.expected, *recorded)
+			}
+		})
+	}
+}
@@ -17,89 +17,356 @@
 package workflow
 
 import (
+	"errors"
 	"fmt"
 	"maps"
 	"os"
 	"strings"
 	"time"
 
+	"github.com/itchyny/gojq"
 	"github.com/rs/zerolog/log"
 	"github.com/serverlessworkflow/sdk-go/v3/model"
+	"go.temporal.io/sdk/temporal"
 	"go.temporal.io/sdk/workflow"
 )
 
 type TemporalWorkflowTask struct {
-	Key      string
-	TaskBase *model.TaskBase
-	Task     TemporalWorkflowFunc
+	Key             string
+	Type            string
+	TaskBase        *model.TaskBase
+	Task            TemporalWorkflowFunc
+	ActivityOptions *workflow.ActivityOptions
+
+	// Labels from the task's metadata, added to the task's metrics
+	Labels map[string]string
+}
+
+// Applies any task-specific activity options to the context
+func (t TemporalWorkflowTask) Context(ctx workflow.Context) workflow.Context {
+	if t.ActivityOptions == nil {
+		return ctx
+	}
+
+	return workflow.WithActivityOptions(ctx, *t.ActivityOptions)
 }
 
 type TemporalWorkflowFunc func(ctx workflow.Context, data *Variables, output map[string]OutputType) error
 
+// Signal to complete a workflow early, with the signal's payload as the
+// final output
+const CompleteSignal = "__complete"
+
 type TemporalWorkflow struct {
+	// Allow the workflow to be completed by the complete signal
+	CompleteSignal bool
+
+	// A hash of the workflow's definition, so a reload can tell if it's changed
+	Definition string
+
+	// The workflow document, set as the $document variable
+	Document *model.Document
+
 	EnvPrefix string
+	HTTPDebug bool
 	Name      string
+	OnCancel  []TemporalWorkflowTask
+	Priority  temporal.Priority
 	Timeout   time.Duration
 	Tasks     []TemporalWorkflowTask
+
+	// The jq function definitions available in every expression
+	JQDefs []*gojq.FuncDef
+
+	// The number of tasks that can be run before the workflow fails. 0 is
+	// unlimited
+	MaxTasks int
+
+	// The views the output can be returned as, chosen in the input
+	OutputViews OutputViews
+
+	// The search attribute the document tags are upserted to
+	TagsSearchAttribute string
+
+	// Record a timeline of the tasks in the output
+	Timeline bool
+
+	// The identity of the worker running the workflow
+	WorkerIdentity string
 }
 
-func (t *TemporalWorkflow) Workflow(ctx workflow.Context, input HTTPData) (map[string]OutputType, error) {
+// Workflow runs the tasks and returns their output. If an output view is chosen
+// in the input, the output is transformed by it before it's returned. If the
+// run is about to time out, the output so far is returned in a Timeout error's
+// details.
+func (t *TemporalWorkflow) Workflow(ctx workflow.Context, input HTTPData) (any, error) {
 	logger := workflow.GetLogger(ctx)
 	logger.Info("Running workflow")
 
 	logger.Debug("Setting workflow options", "StartToCloseTimeout", t.Timeout)
 	ctx = workflow.WithActivityOptions(ctx, workflow.ActivityOptions{
 		StartToCloseTimeout: t.Timeout,
+		Priority:            t.Priority,
 	})
 
+	if err := CheckReservedKeys(input); err != nil {
+		logger.Error("Input sets a reserved variable", "error", err)
+		return nil, temporal.NewNonRetryableApplicationError(err.Error(), string(InputErr), err)
+	}
+
 	vars := &Variables{
-		Data: GetWorkflowInfo(ctx),
+		Data:   GetWorkflowInfo(ctx),
+		jqDefs: t.JQDefs,
 	}
+	// Anything set in the input takes precedence over propagated variables
+	maps.Copy(vars.Data, propagatedValues(ctx))
 	maps.Copy(vars.Data, input)
 	output := map[string]OutputType{}
 
+	if t.HTTPDebug || t.WorkerIdentity != "" {
+		if err := registerStateQuery(ctx, vars, t.WorkerIdentity); err != nil {
+			logger.Error("Error registering state query", "error", err)
+			return nil, fmt.Errorf("error registering state query: %w", err)
+		}
+	}
+
 	// Load in any envvars with the prefix
 	for _, e := range os.Environ() {
 		pair := strings.SplitN(e, "=", 2)
-		if strings.HasPrefix(pair[0], t.EnvPrefix) {
-			vars.Data[pair[0]] = pair[1]
+		if !strings.HasPrefix(pair[0], t.EnvPrefix) {
+			continue
 		}
+		if isReservedKey(pair[0]) {
+			logger.Warn("Ignoring envvar that would shadow a reserved variable", "name", pair[0])
+			continue
+		}
+		vars.Data[pair[0]] = pair[1]
+	}
+
+	wf, err := t.withSkippedTasks(input)
+	if err != nil {
+		logger.Error("Invalid tasks to skip", "error", err)
+		return nil, temporal.NewNonRetryableApplicationError(err.Error(), string(InputErr), err)
 	}
+	delete(vars.Data, SkipTasksKey)
+	delete(vars.Data, StartFromKey)
+
+	// Check the view before anything's run
+	view, err := t.outputView(input)
+	if err != nil {
+		logger.Error("Invalid output view", "error", err)
+		return nil, temporal.NewNonRetryableApplicationError(err.Error(), string(InputErr), err)
+	}
+	delete(vars.Data, OutputViewKey)
+
+	if err := initTaskStatuses(ctx, vars); err != nil {
+		logger.Error("Error registering task status query", "error", err)
+		return nil, fmt.Errorf("error registering task status query: %w", err)
+	}
+
+	if err := t.setDocumentVariables(ctx, vars); err != nil {
+		logger.Error("Error setting document variables", "error", err)
+		return nil, workflowError(err)
+	}
+
+	var tl *timeline
+	if t.Timeline {
+		if ctx, tl, err = t.enableTimeline(ctx); err != nil {
+			logger.Error("Error registering timeline query", "error", err)
+			return nil, fmt.Errorf("error registering timeline query: %w", err)
+		}
+	}
+
+	// Operators can pause the workflow between tasks
+	ctx = enablePause(ctx)
+	ctx = withTaskLimit(ctx, t.MaxTasks)
+
+	run := wf.runTasks
+	if t.CompleteSignal {
+		run = wf.runTasksUntilComplete
+	}
+
+	if err := withRunDeadline(run)(ctx, vars, output); err != nil {
+		if errors.Is(ctx.Err(), workflow.ErrCanceled) {
+			t.runOnCancel(ctx, vars, output)
+		}
+		if errors.Is(err, ErrRunDeadline) {
+			logger.Warn("Workflow run is about to time out - returning the output so far")
+			addTimelineOutput(output, tl)
+			return nil, runDeadlineError(err, output)
+		}
+		return nil, workflowError(err)
+	}
+
+	addTimelineOutput(output, tl)
+
+	if view != nil {
+		result, err := applyOutputView(view, output)
+		if err != nil {
+			logger.Error("Error applying output view", "error", err)
+			return nil, temporal.NewNonRetryableApplicationError(err.Error(), string(OutputViewErr), err)
+		}
+		return result, nil
+	}
+
+	return output, nil
+}
+
+// Runs each of the tasks sequentially
+func (t *TemporalWorkflow) runTasks(ctx workflow.Context, vars *Variables, output map[string]OutputType) error {
+	logger := workflow.GetLogger(ctx)
+	ctx = withPropagatedVariables(ctx, vars)
+	pendingTaskStatuses(vars, t.Tasks)
 
 	for _, task := range t.Tasks {
+		if err := waitIfPaused(ctx, task.Key); err != nil {
+			return err
+		}
+
 		logger.Debug("Check if task can be run", "name", task.Key)
 
 		// Check for and run any if statement
 		if toRun, err := CheckIfStatement(task.TaskBase, vars); err != nil {
+			err = WithExpressionContext(err, task.Key, "if")
 			logger.Error("Error checking if statement", "error", err)
-			return nil, err
+			return err
 		} else if !toRun {
 			logger.Debug("Skipping task as if statement resolved as false", "name", task.Key)
+			recordTimeline(ctx, task, workflow.Now(ctx), TimelineStatusSkipped, nil)
+			setTaskStatus(vars, task.Key, TaskStatusSkipped)
 			continue
 		}
 
+		if err := countTask(ctx, task.Key); err != nil {
+			logger.Error("Too many tasks run - is the workflow looping?", "error", err)
+			return err
+		}
+
 		logger.Info("Running task", "name", task.Key)
-		if err := task.Task(ctx, vars, output); err != nil {
-			return nil, err
+		start := workflow.Now(ctx)
+		setTaskStatus(vars, task.Key, TaskStatusRunning)
+		err := task.Task(task.Context(ctx), vars, output)
+		recordTaskMetrics(ctx, task, start, err)
+		if err != nil {
+			recordTimeline(ctx, task, start, TimelineStatusFailed, err)
+			setTaskStatus(vars, task.Key, TaskStatusFaulted)
+			return WithExpressionContext(err, task.Key, "")
 		}
+		recordTimeline(ctx, task, start, TimelineStatusCompleted, nil)
+		completeTaskStatus(vars, task.Key)
 	}
 
-	return output, nil
+	return nil
+}
+
+// Runs the tasks, unless the complete signal is received first. Any running
+// task is cancelled and the signal's payload is added to the output.
+func (t *TemporalWorkflow) runTasksUntilComplete(ctx workflow.Context, vars *Variables, output map[string]OutputType) error {
+	logger := workflow.GetLogger(ctx)
+
+	tasksCtx, cancel := workflow.WithCancel(ctx)
+	defer cancel()
+
+	future, settable := workflow.NewFuture(ctx)
+	workflow.Go(tasksCtx, func(ctx workflow.Context) {
+		settable.SetError(t.runTasks(ctx, vars, output))
+	})
+
+	var err error
+	var payload HTTPData
+	completed := false
+	workflow.NewSelector(ctx).
+		AddFuture(future, func(f workflow.Future) {
+			err = f.Get(ctx, nil)
+		}).
+		AddReceive(workflow.GetSignalChannel(ctx, CompleteSignal), func(c workflow.ReceiveChannel, more bool) {
+			c.Receive(ctx, &payload)
+			completed = true
+		}).
+		Select(ctx)
+
+	if completed {
+		logger.Info("Workflow completed by signal - remaining tasks skipped")
+		output[CompleteSignal] = OutputType{
+			Type: CompleteResultType,
+			Data: payload,
+		}
+		return nil
+	}
+
+	return err
+}
+
+// Runs the onCancel tasks once the workflow has been cancelled. These run in
+// a disconnected context as the workflow's context is already cancelled. Any
+// error is logged so the workflow still reports as cancelled.
+func (t *TemporalWorkflow) runOnCancel(ctx workflow.Context, vars *Variables, output map[string]OutputType) {
+	if len(t.OnCancel) == 0 {
+		return
+	}
+
+	logger := workflow.GetLogger(ctx)
+	logger.Info("Workflow cancelled - running onCancel tasks")
+
+	cancelCtx, _ := workflow.NewDisconnectedContext(ctx)
+	cancelWf := &TemporalWorkflow{
+		Name:  t.Name,
+		Tasks: t.OnCancel,
+	}
+	if err := cancelWf.runTasks(cancelCtx, vars, output); err != nil {
+		logger.Error("Error running onCancel tasks", "error", err)
+	}
 }
 
-func (w *Workflow) workflowBuilder(tasks *model.TaskList, name string) ([]*TemporalWorkflow, error) {
+// Builds the tasks to run if the workflow is cancelled
+func (w *Workflow) onCancelTasks(tasks *model.TaskList, name string) ([]TemporalWorkflowTask, error) {
+	if tasks == nil {
+		return nil, nil
+	}
+
+	wfs, err := w.workflowBuilder(tasks, name, true)
+	if err != nil {
+		return nil, fmt.Errorf("error building onCancel tasks: %w", err)
+	}
+
+	return wfs[len(wfs)-1].Tasks, nil
+}
+
+// Builds the workflows from the task list. If inline, any do tasks are run
+// within the workflow rather than being registered as additional workflows.
+func (w *Workflow) workflowBuilder(tasks *model.TaskList, name string, inline bool) ([]*TemporalWorkflow, error) {
 	wfs := make([]*TemporalWorkflow, 0)
 
 	timeout := defaultWorkflowTimeout
 	if w.wf.Timeout != nil && w.wf.Timeout.Timeout != nil && w.wf.Timeout.Timeout.After != nil {
-		timeout = ToDuration(w.wf.Timeout.Timeout.After)
+		t, err := ToDuration(w.wf.Timeout.Timeout.After)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing workflow timeout: %w", err)
+		}
+		timeout = t
+	}
+
+	priority, err := w.Priority()
+	if err != nil {
+		return nil, err
 	}
 
 	wf := &TemporalWorkflow{
-		EnvPrefix: w.envPrefix,
-		Name:      name,
-		Tasks:     make([]TemporalWorkflowTask, 0),
-		Timeout:   timeout,
+		CompleteSignal:      w.completeSignal,
+		Document:            &w.wf.Document,
+		EnvPrefix:           w.envPrefix,
+		HTTPDebug:           w.httpDebugSize > 0,
+		MaxTasks:            w.maxTasks,
+		Name:                name,
+		OutputViews:         w.outputViews,
+		Priority:            priority,
+		JQDefs:              w.jqDefs,
+		TagsSearchAttribute: w.tagsSearchAttribute,
+		Tasks:               make([]TemporalWorkflowTask, 0),
+		Timeline:            w.timeline,
+		Timeout:             timeout,
+
+		WorkerIdentity: w.workerIdentity,
 	}
 
 	// Iterate over the task list to build out our workflow(s)
@@ -110,14 +377,23 @@ func (w *Workflow) workflowBuilder(tasks *model.TaskList, name string) ([]*Tempo
 		var additionalWorkflows []*TemporalWorkflow
 
 		if http := item.AsCallHTTPTask(); http != nil {
-			task = httpTaskImpl(http, item.Key)
+			task = httpTaskImpl(http, item.Key, w.callHTTPExtensions[item.Key], w.httpDebugSize, w.httpTaskQueues)
 			taskType = "CallHTTP"
 		}
 
 		if do := item.AsDoTask(); do != nil {
-			additionalWorkflows, err = doTaskImpl(do, item, w)
+			if inline {
+				task, err = doTaskInlineImpl(do, item, w)
+			} else {
+				additionalWorkflows, err = doTaskImpl(do, item, w)
+				wfs = append(wfs, additionalWorkflows...)
+			}
 			taskType = "DoTask"
-			wfs = append(wfs, additionalWorkflows...)
+		}
+
+		if emit := item.AsEmitTask(); emit != nil {
+			task, err = emitTaskImpl(emit, item.Key)
+			taskType = "EmitTask"
 		}
 
 		if fork := item.AsForkTask(); fork != nil {
@@ -126,17 +402,27 @@ func (w *Workflow) workflowBuilder(tasks *model.TaskList, name string) ([]*Tempo
 		}
 
 		if listen := item.AsListenTask(); listen != nil {
-			task, err = listenTaskImpl(listen, item.Key)
+			task, err = listenTaskImpl(listen, item.Key, w)
 			taskType = "ListenTask"
 		}
 
+		if isNoopTask(item) {
+			task, err = noopTaskImpl(item.AsCallFunctionTask(), item.Key)
+			taskType = "NoopTask"
+		}
+
 		if set := item.AsSetTask(); set != nil {
 			task = setTaskImpl(set)
 			taskType = "SetTask"
 		}
 
+		if try := item.AsTryTask(); try != nil {
+			task, err = tryTaskImpl(try, item, w)
+			taskType = "TryTask"
+		}
+
 		if wait := item.AsWaitTask(); wait != nil {
-			task = waitTaskImpl(wait)
+			task, err = waitTaskImpl(wait, item.Key, w)
 			taskType = "WaitTask"
 		}
 
@@ -144,6 +430,13 @@ func (w *Workflow) workflowBuilder(tasks *model.TaskList, name string) ([]*Tempo
 			return nil, err
 		}
 
+		// Custom handlers only run tasks that aren't implemented here
+		if taskType == "" {
+			if task, taskType, err = customTaskImpl(item, w); err != nil {
+				return nil, err
+			}
+		}
+
 		if taskType != "" {
 			log.Debug().Str("key", item.Key).Str("type", taskType).Msg("Task detected")
 		} else {
@@ -151,10 +444,39 @@ func (w *Workflow) workflowBuilder(tasks *model.TaskList, name string) ([]*Tempo
 		}
 
 		if task != nil {
+			labels, err := taskLabels(item.Key, item.GetBase().Metadata)
+			if err != nil {
+				return nil, err
+			}
+
+			activityOptions, err := w.taskActivityOptions(taskType, item.GetBase(), labels, timeout, priority)
+			if err != nil {
+				return nil, fmt.Errorf("error building activity options for task %s: %w", item.Key, err)
+			}
+
+			continueOnError, err := taskContinueOnError(item.Key, item.GetBase().Metadata)
+			if err != nil {
+				return nil, err
+			}
+			if continueOnError {
+				task = withContinueOnError(task, item.Key)
+			}
+
+			outputKey, err := taskOutputKey(item.Key, item.GetBase().Metadata)
+			if err != nil {
+				return nil, err
+			}
+			if outputKey != item.Key {
+				task = withOutputKey(task, item.Key, outputKey)
+			}
+
 			wf.Tasks = append(wf.Tasks, TemporalWorkflowTask{
-				Key:      item.Key,
-				TaskBase: item.GetBase(),
-				Task:     task,
+				Key:             item.Key,
+				Type:            taskType,
+				TaskBase:        item.GetBase(),
+				Task:            task,
+				ActivityOptions: activityOptions,
+				Labels:          labels,
 			})
 		}
 	}
@@ -169,11 +491,18 @@ func (w *Workflow) workflowBuilder(tasks *model.TaskList, name string) ([]*Tempo
 func (w *Workflow) BuildWorkflows() ([]*TemporalWorkflow, error) {
 	wfs := make([]*TemporalWorkflow, 0)
 
-	d, err := w.workflowBuilder(w.wf.Do, w.WorkflowName())
+	d, err := w.workflowBuilder(w.wf.Do, w.WorkflowName(), false)
 	if err != nil {
 		return nil, fmt.Errorf("error building workflows: %w", err)
 	}
 
+	// The root workflow is always last
+	root := d[len(d)-1]
+	root.Definition = w.definitions.root
+	if root.OnCancel, err = w.onCancelTasks(w.rootOnCancel, w.WorkflowName()); err != nil {
+		return nil, err
+	}
+
 	wfs = append(wfs, d...)
 	return wfs, nil
 }
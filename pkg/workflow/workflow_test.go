@@ -0,0 +1,152 @@
+/*
+ * Copyright 2025 Simon Emms <simon@simonemms.com>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package workflow
+
+import (
+	"maps"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/serverlessworkflow/sdk-go/v3/model"
+	"go.temporal.io/sdk/activity"
+	"go.temporal.io/sdk/testsuite"
+	"go.temporal.io/sdk/workflow"
+)
+
+// Writes the document to a workflow file, returning its path
+func writeTestWorkflow(t *testing.T, doc string) string {
+	t.Helper()
+
+	file := filepath.Join(t.TempDir(), "workflow.yaml")
+	if err := os.WriteFile(file, []byte(doc), 0o600); err != nil {
+		t.Fatalf("error writing workflow: %v", err)
+	}
+
+	return file
+}
+
+// Loads the workflow from the document
+func loadTestWorkflow(t *testing.T, doc string, opts ...Option) *Workflow {
+	t.Helper()
+
+	w, err := LoadFromFile(writeTestWorkflow(t, doc), "TSW_", opts...)
+	if err != nil {
+		t.Fatalf("error loading workflow: %v", err)
+	}
+
+	return w
+}
+
+// Builds the workflows, returning the one with the name
+func buildTestWorkflow(t *testing.T, w *Workflow, name string) *TemporalWorkflow {
+	t.Helper()
+
+	wfs, err := w.BuildWorkflows()
+	if err != nil {
+		t.Fatalf("error building workflows: %v", err)
+	}
+	for _, wf := range wfs {
+		if wf.Name == name {
+			return wf
+		}
+	}
+
+	t.Fatalf("workflow %s not built", name)
+	return nil
+}
+
+// Creates a test environment with the activities registered as the worker
+// does
+func newTestEnvironment(w *Workflow) *testsuite.TestWorkflowEnvironment {
+	var suite testsuite.WorkflowTestSuite
+	env := suite.NewTestWorkflowEnvironment()
+
+	a := w.Activities()
+	env.RegisterActivity(a)
+	for _, name := range w.CallHTTPActivityNames() {
+		env.RegisterActivityWithOptions(a.CallHTTP, activity.RegisterOptions{Name: name})
+	}
+
+	return env
+}
+
+// Runs the workflow in the test environment, with the activities registered
+// as the worker does
+func runTestWorkflow(t *testing.T, w *Workflow, wf *TemporalWorkflow, input HTTPData) (map[string]any, error) {
+	t.Helper()
+
+	return runTestWorkflowInEnvironment(t, newTestEnvironment(w), wf, input)
+}
+
+// Runs the workflow in the test environment, such as one with callbacks
+// registered
+func runTestWorkflowInEnvironment(
+	t *testing.T,
+	env *testsuite.TestWorkflowEnvironment,
+	wf *TemporalWorkflow,
+	input HTTPData,
+) (map[string]any, error) {
+	t.Helper()
+
+	env.ExecuteWorkflow(wf.Workflow, input)
+	if !env.IsWorkflowCompleted() {
+		t.Fatal("workflow didn't complete")
+	}
+	if err := env.GetWorkflowError(); err != nil {
+		return nil, err
+	}
+
+	var output map[string]any
+	if err := env.GetWorkflowResult(&output); err != nil {
+		t.Fatalf("error getting workflow result: %v", err)
+	}
+
+	return output, nil
+}
+
+// Registers the "call: record" task, which records the keys of the tasks in
+// the order they're run
+func recordTasks(t *testing.T) *[]string {
+	t.Helper()
+
+	recorded := make([]string, 0)
+	RegisterTaskHandler("call.record", func(task *model.TaskItem, w *Workflow) (TemporalWorkflowFunc, error) {
+		return func(ctx workflow.Context, data *Variables, output map[string]OutputType) error {
+			recorded = append(recorded, task.Key)
+			return nil
+		}, nil
+	})
+
+	return &recorded
+}
+
+// Registers the "call: capture" task, which copies the variables when it's
+// run
+func captureVars(t *testing.T) *HTTPData {
+	t.Helper()
+
+	vars := HTTPData{}
+	RegisterTaskHandler("call.capture", func(task *model.TaskItem, w *Workflow) (TemporalWorkflowFunc, error) {
+		return func(ctx workflow.Context, data *Variables, output map[string]OutputType) error {
+			vars = maps.Clone(data.Data)
+			return nil
+		}, nil
+	})
+
+	return &vars
+}